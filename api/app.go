@@ -1,157 +1,430 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"net/url"
+	"io"
+	"os"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	_authorRepoMysql "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/migrations"
+	_adminDelivery "github.com/ilmimris/poc-gofiber-clean-arch/pkg/admin/delivery/rest"
+	_auditDelivery "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/delivery/rest"
+	_auditUsecase "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/usecase"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/breaker"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/cache"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/database"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/health"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/lifecycle"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/metrics"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/idempotency"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/ratelimit"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/migration"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/retry"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/tracing"
 	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
 
-	_authorRepoPsql "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/psql"
+	_healthDelivery "github.com/ilmimris/poc-gofiber-clean-arch/pkg/health/delivery/rest"
 	_postDelivery "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/delivery/rest"
-	_postRepoMysql "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/mysql"
-
-	_postRepoPsql "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/psql"
 	_postUsecase "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/usecase"
-	"github.com/spf13/viper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 
 	_ "github.com/go-sql-driver/mysql"
+	_auditRepoMemory "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/repository/memory"
+	_auditRepoSQL "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/repository/sqlrepo"
+	_authorRepoMemory "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/memory"
+	_authorRepoSQL "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/sqlrepo"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/timeout"
+	_postRepoMemory "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/memory"
+	_postRepoSQL "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/sqlrepo"
 	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
 )
 
-func init() {
-	viper.SetConfigFile(`config.json`)
-	err := viper.ReadInConfig()
+// newLogger creates the JSON logger configured in log.level
+func newLogger(cfg *config.Config) (*logrus.Logger, error) {
+	logger, err := logging.New(cfg.Log, cfg.Debug, os.Stderr)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	if viper.GetBool(`debug`) {
-		log.Println("Service RUN on DEBUG mode")
-	}
+	logger.Debug("Service RUN on DEBUG mode")
+	return logger, nil
 }
 
-func databaseConnectionPsql() (*sql.DB, error) {
-	// Read db configuration
-	dbHost := viper.GetString(`database.host`)
-	dbPort := viper.GetString(`database.port`)
-	dbUser := viper.GetString(`database.user`)
-	dbPass := viper.GetString(`database.pass`)
-	dbName := viper.GetString(`database.name`)
-
-	val := url.Values{}
-
-	// Config db postgres
-	connection := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", dbUser, dbPass, dbHost, dbPort, dbName)
-	val.Add("sslmode", "disable")
-
-	dsn := fmt.Sprintf("%s?%s", connection, val.Encode())
+// openDatabase connects to the database configured in database.kind
+func openDatabase(cfg config.DatabaseConfig, logger logrus.FieldLogger) (dbKind string, db *sql.DB, err error) {
+	dbKind = cfg.Kind
 
-	// Connect with database
-	dbConn, err := sql.Open(`postgres`, dsn)
+	// db stays nil, the repositories keep the data in the process
+	if dbKind == "memory" {
+		logger.WithField("kind", dbKind).Info("keeping the data in memory")
+		return
+	}
 
+	db, err = database.Open(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return
 	}
-	err = dbConn.Ping()
 
+	logger.WithField("kind", dbKind).Info("database connected")
+	return
+}
+
+// newMigrator will create a migrator for the embedded migrations of the given database kind
+func newMigrator(dbKind string, db *sql.DB, logger logrus.FieldLogger) (*migration.Migrator, error) {
+	source, err := migrations.Source(dbKind)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return dbConn, err
+	return migration.New(db, dbKind, source, logger)
 }
 
-func databaseConnectionMysql() (*sql.DB, error) {
-	// Read db configuration
-	dbHost := viper.GetString(`database.host`)
-	dbPort := viper.GetString(`database.port`)
-	dbUser := viper.GetString(`database.user`)
-	dbPass := viper.GetString(`database.pass`)
-	dbName := viper.GetString(`database.name`)
+// migrateDatabase applies the pending embedded migrations when database.migrate is enabled
+func migrateDatabase(cfg config.DatabaseConfig, db *sql.DB, logger logrus.FieldLogger) error {
+	if !cfg.Migrate || db == nil {
+		return nil
+	}
 
-	val := url.Values{}
+	migrator, err := newMigrator(cfg.Kind, db, logger)
+	if err != nil {
+		return err
+	}
 
-	// Config db mysql
-	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, dbPort, dbName)
-	val.Add("parseTime", "1")
-	val.Add("loc", "Asia/Jakarta")
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
 
-	dsn := fmt.Sprintf("%s?%s", connection, val.Encode())
+	logger.WithField("applied", len(applied)).Info("database migrated")
+	return nil
+}
 
-	// Connect with database
-	dbConn, err := sql.Open(`mysql`, dsn)
+// container holds the dependencies shared by every command
+type container struct {
+	cfg       *config.Config
+	logger    *logrus.Logger
+	dbKind    string
+	db        *sql.DB            // nil for database.kind memory
+	dialect   repository.Dialect // nil for database.kind memory
+	router    *repository.Router // nil for database.kind memory
+	lifecycle *lifecycle.Manager
+	health    *health.Registry
+
+	// metrics and registry are nil when metrics are disabled
+	metrics  *metrics.Metrics
+	registry *prometheus.Registry
+
+	// tracer is nil when tracing is disabled
+	tracer trace.TracerProvider
+
+	postRepo   domain.PostRepository
+	authorRepo domain.AuthorRepository
+	auditRepo  domain.AuditRepository
+	txManager  domain.TxManager
+
+	postUcase  domain.PostUsecase
+	auditUcase domain.AuditUsecase
+}
 
+// newContainer connects to the database and wires the repositories and usecases on top of it
+func newContainer(cfg *config.Config) (*container, error) {
+	logger, err := newLogger(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	err = dbConn.Ping()
 
+	dbKind, db, err := openDatabase(cfg.Database, logger)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return dbConn, err
-}
+	c := &container{
+		cfg:       cfg,
+		logger:    logger,
+		dbKind:    dbKind,
+		db:        db,
+		lifecycle: lifecycle.New(logger),
+		health:    health.NewRegistry(time.Duration(cfg.Health.Timeout) * time.Millisecond),
+	}
 
-func main() {
-	dbKind := viper.GetString(`database.kind`)
+	if db != nil {
+		c.lifecycle.OnStop("database", func(ctx context.Context) error {
+			return db.Close()
+		})
+	}
 
-	var db *sql.DB
-	var err error
+	if cfg.Metrics.Enabled {
+		c.registry = prometheus.NewRegistry()
+		c.metrics = metrics.New(c.registry)
+	}
 
+	var dialect repository.Dialect
 	switch dbKind {
-	case "mysql":
-		db, err = databaseConnectionMysql()
-	case "postgres":
-		db, err = databaseConnectionPsql()
+	case "memory":
+		c.postRepo = _postRepoMemory.NewMemoryPostRepository()
+		c.authorRepo = _authorRepoMemory.NewMemoryAuthorRepository()
+		c.auditRepo = _auditRepoMemory.NewMemoryAuditRepository()
+	default:
+		dialect, err = repository.DialectFor(dbKind)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		c.dialect = dialect
+
+		replicas, err := database.OpenReplicas(cfg.Database)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		replicasCfg := cfg.Database.Replicas
+		c.router = repository.NewRouter(db, replicas, time.Duration(replicasCfg.ReadYourWrites)*time.Millisecond, logger)
+		c.lifecycle.OnStop("replicas", func(ctx context.Context) error {
+			return c.router.Close()
+		})
+		if len(replicas) > 0 {
+			interval := time.Duration(replicasCfg.CheckInterval) * time.Millisecond
+			c.router.CheckReplicas(context.Background(), interval)
+			c.lifecycle.Go("replicas", func(ctx context.Context) {
+				c.router.Watch(ctx, interval)
+			})
+			logger.WithField("replicas", len(replicas)).Info("routing the reads to the replicas")
+		}
+
+		c.postRepo = _postRepoSQL.NewSQLPostRepository(c.router, dialect, logger)
+		c.authorRepo = _authorRepoSQL.NewSQLAuthorRepository(c.router, dialect)
+		c.auditRepo = _auditRepoSQL.NewSQLAuditRepository(c.router, dialect, logger)
+
+		// registered after the database, the prepared statements are closed before the connections
+		closers := []io.Closer{c.postRepo.(io.Closer), c.authorRepo.(io.Closer), c.auditRepo.(io.Closer)}
+		c.lifecycle.OnStop("repositories", func(ctx context.Context) (err error) {
+			for _, closer := range closers {
+				if errClose := closer.Close(); errClose != nil && err == nil {
+					err = errClose
+				}
+			}
+			return
+		})
+
+		var observer retry.Observer
+		if c.metrics != nil {
+			observer = c.metrics
+		}
+		retryCfg := cfg.Database.Retry
+		retrier := retry.NewRetrier(retry.Policy{
+			Attempts:  retryCfg.Attempts,
+			BaseDelay: time.Duration(retryCfg.BaseDelay) * time.Millisecond,
+			MaxDelay:  time.Duration(retryCfg.MaxDelay) * time.Millisecond,
+		}, dialect.Transient, observer)
+
+		c.postRepo = retry.NewPostRepository(c.postRepo, retrier)
+		c.authorRepo = retry.NewAuthorRepository(c.authorRepo, retrier)
+		c.auditRepo = retry.NewAuditRepository(c.auditRepo, retrier)
+
+		if breakerCfg := cfg.Database.Breaker; breakerCfg.Enabled {
+			// one breaker for the database, every repository calling it fails fast together
+			b := breaker.NewBreaker(dbKind, breaker.Config{
+				Window:      time.Duration(breakerCfg.Window) * time.Millisecond,
+				MinCalls:    breakerCfg.MinCalls,
+				ErrorRate:   breakerCfg.ErrorRate,
+				OpenTimeout: time.Duration(breakerCfg.OpenTimeout) * time.Millisecond,
+			}, logger)
+
+			var stale *cache.LRU
+			if breakerCfg.StaleEntries > 0 {
+				stale = cache.NewLRU(breakerCfg.StaleEntries)
+			}
+
+			c.postRepo = breaker.NewPostRepository(c.postRepo, b, stale)
+			c.authorRepo = breaker.NewAuthorRepository(c.authorRepo, b, stale)
+		}
+	}
+
+	if c.metrics != nil {
+		if db != nil {
+			c.registry.MustRegister(metrics.NewDBStatsCollector("primary", db))
+			for i, replica := range c.router.Replicas {
+				c.registry.MustRegister(metrics.NewDBStatsCollector(fmt.Sprintf("replica-%d", i), replica))
+			}
+		}
+
+		c.postRepo = metrics.NewPostRepository(c.postRepo, c.metrics)
 	}
-	defer func() {
-		err := db.Close()
+
+	if cfg.Tracing.Exporter != config.TracingExporterNone {
+		tp, shutdown, err := tracing.NewProvider(context.Background(), cfg.Tracing)
 		if err != nil {
-			log.Fatal(err)
+			if db != nil {
+				db.Close()
+			}
+			return nil, err
 		}
-	}()
-	if err != nil {
-		log.Fatalf("Database Connection error: %s", err)
+		c.tracer = tp
+		c.lifecycle.OnStop("tracing", shutdown)
+
+		c.postRepo = tracing.NewPostRepository(c.postRepo, tp, dbKind)
+		c.authorRepo = tracing.NewAuthorRepository(c.authorRepo, tp, dbKind)
 	}
-	fmt.Println("Database connection success!")
 
-	var postRepo domain.PostRepository
-	var authorRepo domain.AuthorRepository
+	if db != nil {
+		c.health.Register(health.NewDBChecker(db))
 
-	switch dbKind {
-	case "mysql":
-		postRepo = _postRepoMysql.NewMysqlPostRepository(db)
-		authorRepo = _authorRepoMysql.NewMysqlAuthorRepository(db)
-	case "postgres":
-		postRepo = _postRepoPsql.NewPsqlPostRepository(db)
-		authorRepo = _authorRepoPsql.NewPsqlAuthorRepository(db)
+		migrator, err := newMigrator(dbKind, db, logger)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		c.health.Register(health.NewMigrationChecker(migrator))
+
+		c.txManager = repository.NewSQLTxManager(db, dialect, logger)
+	} else {
+		// the in-memory repositories apply every write at once, there is nothing to roll back
+		c.txManager = repository.NewNoopTxManager()
 	}
 
-	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
+	timeoutContext := time.Duration(cfg.Context.Timeout) * time.Second
 
-	postUcase := _postUsecase.NewPostUsecase(postRepo, authorRepo, timeoutContext)
+	var postCache *cache.Cache
+	if cfg.Cache.Enabled {
+		var observer cache.Observer
+		if c.metrics != nil {
+			observer = c.metrics
+		}
+		backend := cache.NewMemoryBackend(cfg.Cache.Size)
+		ttl := time.Duration(cfg.Cache.TTL) * time.Second
+
+		postCache = cache.NewCache("post", backend, ttl, timeoutContext, observer, logger)
+		c.authorRepo = cache.NewAuthorRepository(c.authorRepo, cache.NewCache("author", backend, ttl, timeoutContext, observer, logger))
+	}
+
+	c.postUcase = _postUsecase.NewPostUsecase(c.postRepo, c.authorRepo, c.auditRepo, c.txManager, timeoutContext, logger)
+	c.auditUcase = _auditUsecase.NewAuditUsecase(c.auditRepo, timeoutContext)
+
+	if postCache != nil {
+		c.postUcase = cache.NewPostUsecase(c.postUcase, postCache)
+	}
+
+	if c.metrics != nil {
+		c.postUcase = metrics.NewPostUsecase(c.postUcase, c.metrics)
+	}
+	if c.tracer != nil {
+		c.postUcase = tracing.NewPostUsecase(c.postUcase, c.tracer)
+	}
+
+	return c, nil
+}
+
+// Shutdown stops the background workers and then releases the database connection
+func (c *container) Shutdown(ctx context.Context) error {
+	return c.lifecycle.Shutdown(ctx)
+}
 
-	// Create a Fiber app
+// newServer creates the fiber app serving the REST api
+func newServer(c *container) *fiber.App {
 	app := fiber.New()
+	app.Use(logging.Middleware(c.logger))
 	app.Use(cors.New())
 
-	// Use loggoer middleware
-	app.Use(logger.New())
+	if c.tracer != nil {
+		app.Use(tracing.Middleware(c.tracer))
+	}
+
+	useTimeout(app, c.cfg.Context)
+
+	if c.metrics != nil {
+		app.Use(c.metrics.Middleware())
+		app.Get(c.cfg.Metrics.Path, metrics.Handler(c.registry))
+	}
+
+	useRateLimiter(app, c.cfg.RateLimit, c.db, c.dialect, c.logger)
+	useIdempotency(app, c.cfg.Idempotency, c.db, c.dialect, c.logger)
 
 	app.Get("/", func(ctx *fiber.Ctx) error {
 		return ctx.Send([]byte("Welcome to the clean-architecture!"))
 	})
 
-	_postDelivery.NewPostHandler(app, postUcase)
+	_postDelivery.NewPostHandler(app, c.postUcase, c.logger)
+	_auditDelivery.NewAuditHandler(app, c.auditUcase, c.logger)
+	_healthDelivery.NewHealthHandler(app, c.health)
+
+	// the pool statistics are those of the sql database
+	if c.cfg.Admin.Enabled && c.db != nil {
+		_adminDelivery.NewAdminHandler(app, c.cfg.Admin.Token, c.db)
+	}
 
-	log.Fatal(app.Listen(viper.GetString(`server.address`)))
+	return app
+}
+
+func useTimeout(app *fiber.App, cfg config.ContextConfig) {
+	routes := make(map[string]time.Duration, len(cfg.Routes))
+	for prefix, seconds := range cfg.Routes {
+		routes[prefix] = time.Duration(seconds) * time.Second
+	}
+
+	app.Use(timeout.New(timeout.Config{
+		Timeout: time.Duration(cfg.Timeout) * time.Second,
+		Routes:  routes,
+	}))
+}
+
+func useRateLimiter(app *fiber.App, cfg config.RateLimitConfig, db *sql.DB, dialect repository.Dialect, logger logrus.FieldLogger) {
+	if !cfg.Enabled {
+		return
+	}
+
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "sql":
+		store = ratelimit.NewSQLStore(db, dialect, logger)
+	default:
+		store = ratelimit.NewMemoryStore()
+	}
+
+	for prefix, group := range cfg.Groups {
+		keyFunc, err := ratelimit.KeyFuncByName(group.Key)
+		if err != nil {
+			logger.WithError(err).Fatal("rate limit config error")
+		}
+
+		app.Use(prefix, ratelimit.New(ratelimit.Config{
+			Name:    prefix,
+			Limit:   group.Limit,
+			Window:  time.Duration(group.Window) * time.Second,
+			KeyFunc: keyFunc,
+			Store:   store,
+			Logger:  logger,
+		}))
+	}
+}
+
+func useIdempotency(app *fiber.App, cfg config.IdempotencyConfig, db *sql.DB, dialect repository.Dialect, logger logrus.FieldLogger) {
+	if !cfg.Enabled {
+		return
+	}
+
+	var store idempotency.Store
+	switch cfg.Store {
+	case "sql":
+		store = idempotency.NewSQLStore(db, dialect, logger)
+	default:
+		store = idempotency.NewMemoryStore()
+	}
 
+	app.Use(idempotency.New(idempotency.Config{
+		TTL:    time.Duration(cfg.TTL) * time.Second,
+		Wait:   time.Duration(cfg.Wait) * time.Millisecond,
+		Store:  store,
+		Logger: logger,
+	}))
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJSONRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// TestMemoryEndToEnd drives the REST api wired on the in-memory repositories
+func TestMemoryEndToEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"database": {"kind": "memory"}, "log": {"level": "error"}}`), 0600))
+
+	cfg, err := config.Load(path, nil)
+	require.NoError(t, err)
+
+	c, err := newContainer(cfg)
+	require.NoError(t, err)
+	defer c.Shutdown(context.TODO())
+	app := newServer(c)
+
+	author := domain.Author{Name: "Iman Tumorang"}
+	require.NoError(t, c.authorRepo.Store(context.TODO(), &author))
+
+	res, err := app.Test(newJSONRequest(http.MethodPost, "/posts", `{"title":"Hello","content":"Content","author":{"id":1}}`), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res, err = app.Test(newJSONRequest(http.MethodPost, "/posts", `{"title":"Hello","content":"Again","author":{"id":1}}`), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	res, err = app.Test(httptest.NewRequest(http.MethodGet, "/posts/1", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var post domain.Post
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&post))
+	assert.Equal(t, "Hello", post.Title)
+	assert.Equal(t, "Iman Tumorang", post.Author.Name)
+
+	res, err = app.Test(httptest.NewRequest(http.MethodDelete, "/posts/1", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	res, err = app.Test(httptest.NewRequest(http.MethodGet, "/posts/1", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/spf13/cobra"
+)
+
+func newAuthorsCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "authors",
+		Short: "Manage the authors",
+	}
+
+	var name string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create an author and print its id",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.New("the author needs a --name")
+			}
+
+			return withContainer(cfg, func(c *container) error {
+				now := time.Now()
+				author := domain.Author{
+					Name:      name,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+
+				err := c.authorRepo.Store(commandContext(cmd), &author)
+				if err != nil {
+					return err
+				}
+
+				fmt.Println(author.ID)
+				return nil
+			})
+		},
+	}
+	create.Flags().StringVar(&name, "name", "", "name of the author")
+
+	cmd.AddCommand(create)
+
+	return cmd
+}
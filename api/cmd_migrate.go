@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/migration"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema",
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recently applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrator(cfg, func(m *migration.Migrator) error {
+				reverted, err := m.Down(cmd.Context(), steps)
+				if err != nil {
+					return err
+				}
+
+				for _, mig := range reverted {
+					fmt.Printf("Reverted %d_%s\n", mig.Version, mig.Name)
+				}
+				return nil
+			})
+		},
+	}
+	down.Flags().IntVarP(&steps, "steps", "n", 1, "number of migrations to revert")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(cfg, func(m *migration.Migrator) error {
+					applied, err := m.Up(cmd.Context())
+					if err != nil {
+						return err
+					}
+
+					for _, mig := range applied {
+						fmt.Printf("Applied %d_%s\n", mig.Version, mig.Name)
+					}
+					return nil
+				})
+			},
+		},
+		down,
+		&cobra.Command{
+			Use:   "status",
+			Short: "List the migrations and whether they have been applied",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(cfg, func(m *migration.Migrator) error {
+					statuses, err := m.Status(cmd.Context())
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+					for _, s := range statuses {
+						state, appliedAt := "pending", ""
+						if s.Applied {
+							state, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+						}
+						if s.Unknown {
+							state = "unknown"
+						}
+						if s.Dirty {
+							state = "dirty"
+						}
+						fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+					}
+					return w.Flush()
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "force VERSION",
+			Short: "Mark the database as cleanly migrated up to VERSION without running anything",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := strconv.ParseInt(args[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid version %q", args[0])
+				}
+
+				return withMigrator(cfg, func(m *migration.Migrator) error {
+					return m.Force(cmd.Context(), version)
+				})
+			},
+		},
+	)
+
+	return cmd
+}
+
+// withMigrator runs fn with a migrator of the configured database, without wiring the rest
+func withMigrator(cfg *config.Config, fn func(m *migration.Migrator) error) (err error) {
+	if cfg.Database.Kind == "memory" {
+		return errors.New("database.kind memory has no schema to migrate")
+	}
+
+	logger, err := newLogger(cfg)
+	if err != nil {
+		return
+	}
+
+	dbKind, db, err := openDatabase(cfg.Database, logger)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		errClose := db.Close()
+		if errClose != nil {
+			logger.WithError(errClose).Error("closing the database")
+		}
+	}()
+
+	m, err := newMigrator(dbKind, db, logger)
+	if err != nil {
+		return
+	}
+
+	return fn(m)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// exportPageSize is how many posts export fetches per page
+const exportPageSize = 100
+
+func newPostsCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "posts",
+		Short: "Import and export the posts",
+	}
+
+	var exportFile string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write every post as one JSON object per line",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			out, closeOut, err := openOutput(exportFile)
+			if err != nil {
+				return
+			}
+			defer func() {
+				if errClose := closeOut(); err == nil {
+					err = errClose
+				}
+			}()
+
+			return withContainer(cfg, func(c *container) error {
+				n, err := exportPosts(commandContext(cmd), c.postUcase, out)
+				if err != nil {
+					return err
+				}
+
+				c.logger.WithField("exported", n).Info("posts exported")
+				return nil
+			})
+		},
+	}
+	export.Flags().StringVarP(&exportFile, "file", "f", "-", "file to write, - for stdout")
+
+	var importFile string
+	imp := &cobra.Command{
+		Use:   "import",
+		Short: "Create the posts read as one JSON object per line, skipping already existing titles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			in, closeIn, err := openInput(importFile)
+			if err != nil {
+				return
+			}
+			defer func() {
+				if errClose := closeIn(); err == nil {
+					err = errClose
+				}
+			}()
+
+			return withContainer(cfg, func(c *container) error {
+				imported, skipped, err := importPosts(commandContext(cmd), c.postUcase, in)
+				c.logger.WithFields(logrus.Fields{"imported": imported, "skipped": skipped}).Info("posts imported")
+				return err
+			})
+		},
+	}
+	imp.Flags().StringVarP(&importFile, "file", "f", "-", "file to read, - for stdin")
+
+	cmd.AddCommand(export, imp)
+
+	return cmd
+}
+
+func exportPosts(ctx context.Context, pu domain.PostUsecase, w io.Writer) (n int, err error) {
+	enc := json.NewEncoder(w)
+
+	cursor := ""
+	for {
+		var posts []domain.Post
+		posts, cursor, err = pu.Fetch(ctx, cursor, exportPageSize)
+		if err != nil {
+			return
+		}
+
+		for _, post := range posts {
+			if err = enc.Encode(post); err != nil {
+				return
+			}
+			n++
+		}
+
+		if len(posts) < exportPageSize || cursor == "" {
+			return
+		}
+	}
+}
+
+func importPosts(ctx context.Context, pu domain.PostUsecase, r io.Reader) (imported, skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var post domain.Post
+		if err = json.Unmarshal(scanner.Bytes(), &post); err != nil {
+			return imported, skipped, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// the id is assigned by the database, the timestamps are kept and set to now when missing
+		post.ID = 0
+
+		err = pu.Store(ctx, &post)
+		if errors.Is(err, domain.ErrConflict) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return imported, skipped, fmt.Errorf("line %d: %w", line, err)
+		}
+		imported++
+	}
+
+	return imported, skipped, scanner.Err()
+}
+
+func openOutput(file string) (io.Writer, func() error, error) {
+	if file == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, f.Close, nil
+}
+
+func openInput(file string) (io.Reader, func() error, error) {
+	if file == "-" {
+		return os.Stdin, func() error { return nil }, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, f.Close, nil
+}
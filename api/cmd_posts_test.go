@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	mocks "github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	_postRepoSQL "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/sqlrepo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPosts(t *testing.T) {
+	mockUCase := new(mocks.PostUsecase)
+
+	firstPage := make([]domain.Post, exportPageSize)
+	for i := range firstPage {
+		firstPage[i] = domain.Post{ID: int64(i + 1), Title: "Title"}
+	}
+	mockUCase.On("Fetch", mock.Anything, "", int64(exportPageSize)).Return(firstPage, "next", nil).Once()
+	mockUCase.On("Fetch", mock.Anything, "next", int64(exportPageSize)).Return([]domain.Post{{ID: 101}}, "", nil).Once()
+
+	var out bytes.Buffer
+	n, err := exportPosts(context.TODO(), mockUCase, &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, exportPageSize+1, n)
+	assert.Equal(t, exportPageSize+1, strings.Count(out.String(), "\n"))
+	mockUCase.AssertExpectations(t)
+}
+
+// fetchUsecase pages the posts straight from a repository
+type fetchUsecase struct {
+	domain.PostUsecase
+	repo domain.PostRepository
+}
+
+func (u fetchUsecase) Fetch(ctx context.Context, cursor string, num int64) ([]domain.Post, string, error) {
+	return u.repo.Fetch(ctx, cursor, num)
+}
+
+func TestExportPostsSameTimestamp(t *testing.T) {
+	db, d := repositorytest.OpenDatabase(t, "sqlite")
+	postRepo := _postRepoSQL.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+
+	// imported posts created within the same second, more of them than fit in a page
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	total := exportPageSize + exportPageSize/2
+	for i := 0; i < total; i++ {
+		post := domain.Post{Title: fmt.Sprintf("post %d", i), Content: "Content", Author: domain.Author{ID: 1}, CreatedAt: createdAt, UpdatedAt: createdAt}
+		require.NoError(t, postRepo.Store(context.TODO(), &post))
+	}
+
+	var out bytes.Buffer
+	n, err := exportPosts(context.TODO(), fetchUsecase{repo: postRepo}, &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, total, n)
+
+	titles := map[string]bool{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var post domain.Post
+		require.NoError(t, dec.Decode(&post))
+		titles[post.Title] = true
+	}
+	assert.Len(t, titles, total)
+}
+
+func TestImportPosts(t *testing.T) {
+	mockUCase := new(mocks.PostUsecase)
+
+	var in bytes.Buffer
+	enc := json.NewEncoder(&in)
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, enc.Encode(domain.Post{ID: 5, Title: "New", Content: "Content", CreatedAt: createdAt, UpdatedAt: createdAt}))
+	in.WriteString("\n")
+	require.NoError(t, enc.Encode(domain.Post{ID: 6, Title: "Existing", Content: "Content"}))
+
+	mockUCase.On("Store", mock.Anything, mock.MatchedBy(func(p *domain.Post) bool {
+		return p.Title == "New" && p.ID == 0 && p.CreatedAt.Equal(createdAt) && p.UpdatedAt.Equal(createdAt)
+	})).Return(nil).Once()
+	mockUCase.On("Store", mock.Anything, mock.MatchedBy(func(p *domain.Post) bool {
+		return p.Title == "Existing"
+	})).Return(domain.ErrConflict).Once()
+
+	imported, skipped, err := importPosts(context.TODO(), mockUCase, &in)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+	assert.Equal(t, 1, skipped)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestImportPostsInvalidLine(t *testing.T) {
+	mockUCase := new(mocks.PostUsecase)
+
+	_, _, err := importPosts(context.TODO(), mockUCase, strings.NewReader("{not json}\n"))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/spf13/cobra"
+)
+
+//go:embed seed.json
+var seedData []byte
+
+// seed represent the sample data written by the seed command
+type seed struct {
+	Author domain.Author `json:"author"`
+	Posts  []domain.Post `json:"posts"`
+}
+
+// errSeeded is returned by seedDatabase when the sample data is already there
+var errSeeded = errors.New("database is already seeded")
+
+func newSeedCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "seed",
+		Short: "Fill an empty database with a sample author and posts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withContainer(cfg, func(c *container) error {
+				authorID, stored, err := seedDatabase(commandContext(cmd), c)
+				if errors.Is(err, errSeeded) {
+					fmt.Println("Database is already seeded")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Seeded author %d with %d post(s)\n", authorID, stored)
+				return nil
+			})
+		},
+	}
+}
+
+// seedDatabase stores the sample author and its posts, skipping the posts whose title is taken
+func seedDatabase(ctx context.Context, c *container) (authorID int64, stored int, err error) {
+	var s seed
+	err = json.Unmarshal(seedData, &s)
+	if err != nil {
+		return
+	}
+
+	// the first post tells whether the database has already been seeded
+	_, err = c.postRepo.GetByTitle(ctx, s.Posts[0].Title)
+	if err == nil {
+		return 0, 0, errSeeded
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return
+	}
+
+	now := time.Now()
+	s.Author.CreatedAt, s.Author.UpdatedAt = now, now
+	err = c.authorRepo.Store(ctx, &s.Author)
+	if err != nil {
+		return
+	}
+
+	for _, post := range s.Posts {
+		post.Author = s.Author
+		post.CreatedAt, post.UpdatedAt = now, now
+
+		err = c.postUcase.Store(ctx, &post)
+		if errors.Is(err, domain.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		stored++
+	}
+
+	return s.Author.ID, stored, nil
+}
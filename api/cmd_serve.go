@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the REST api until SIGINT or SIGTERM",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withContainer(cfg, func(c *container) error {
+				err := migrateDatabase(cfg.Database, c.db, c.logger)
+				if err != nil {
+					return err
+				}
+
+				// the in-memory database starts empty on every run, so the demo starts from the sample data
+				if c.dbKind == "memory" {
+					_, stored, err := seedDatabase(commandContext(cmd), c)
+					if err != nil {
+						return err
+					}
+					c.logger.WithField("posts", stored).Info("database seeded")
+				}
+
+				ln, err := net.Listen("tcp4", cfg.Server.Address)
+				if err != nil {
+					return err
+				}
+
+				// the database is closed by withContainer once the server has drained
+				return runServer(cmd.Context(), newServer(c), ln, time.Duration(cfg.Server.ShutdownTimeout)*time.Second, c.logger)
+			})
+		},
+	}
+}
+
+// runServer serves app on ln until SIGINT, SIGTERM or the end of ctx. It then stops accepting
+// connections and waits up to timeout for the in-flight requests to finish.
+func runServer(ctx context.Context, app *fiber.App, ln net.Listener, timeout time.Duration, logger logrus.FieldLogger) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- app.Listener(ln)
+	}()
+
+	select {
+	case err := <-errServe:
+		return err
+	case <-ctx.Done():
+	}
+
+	// a second signal kills the process right away
+	stop()
+	logger.WithField("timeout", timeout.String()).Info("shutting down, waiting for the in-flight requests")
+
+	errShutdown := make(chan error, 1)
+	go func() {
+		errShutdown <- app.Shutdown()
+	}()
+
+	select {
+	case err := <-errShutdown:
+		if err != nil {
+			return err
+		}
+	case <-time.After(timeout):
+		return fmt.Errorf("shutdown: requests still in flight after %s", timeout)
+	}
+
+	return <-errServe
+}
@@ -0,0 +1,113 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunServerDrainsOnSIGTERM(t *testing.T) {
+	started := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "http://" + ln.Addr().String()
+
+	errRun := make(chan error, 1)
+	go func() {
+		errRun <- runServer(context.Background(), app, ln, 5*time.Second, logrus.New())
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		slow <- result{status: res.StatusCode, body: string(body), err: err}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow request never reached the handler")
+	}
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	// the in-flight request completes
+	r := <-slow
+	require.NoError(t, r.err)
+	assert.Equal(t, http.StatusOK, r.status)
+	assert.Equal(t, "done", r.body)
+
+	select {
+	case err := <-errRun:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runServer did not return after SIGTERM")
+	}
+
+	// and no new connection is accepted
+	_, err = net.DialTimeout("tcp4", ln.Addr().String(), time.Second)
+	assert.Error(t, err)
+}
+
+func TestRunServerShutdownDeadline(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/stuck", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errRun := make(chan error, 1)
+	go func() {
+		errRun <- runServer(ctx, app, ln, 100*time.Millisecond, logrus.New())
+	}()
+
+	go http.Get("http://" + ln.Addr().String() + "/stuck")
+	<-started
+	cancel()
+
+	select {
+	case err := <-errRun:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runServer ignored the shutdown deadline")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	var configFile string
+	cfg := &config.Config{}
+
+	cmd := &cobra.Command{
+		Use:          "engine",
+		Short:        "Post service of the clean-architecture example",
+		SilenceUsage: true,
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&configFile, "config", "c", "", "config file, defaults to $"+config.EnvConfigFile+" or "+config.DefaultFile)
+	flags.Bool("debug", false, "run on debug mode")
+	flags.String("address", "", "address the REST api listens on")
+	flags.String("db-kind", "", "database kind, mysql, postgres, sqlite or memory")
+	flags.String("db-host", "", "database host")
+	flags.String("db-port", "", "database port")
+	flags.String("db-name", "", "database name, or file for sqlite")
+	flags.String("db-user", "", "database user")
+
+	// the config key each flag overrides
+	bindings := map[string]*pflag.Flag{
+		"debug":          flags.Lookup("debug"),
+		"server.address": flags.Lookup("address"),
+		"database.kind":  flags.Lookup("db-kind"),
+		"database.host":  flags.Lookup("db-host"),
+		"database.port":  flags.Lookup("db-port"),
+		"database.name":  flags.Lookup("db-name"),
+		"database.user":  flags.Lookup("db-user"),
+	}
+
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		loaded, err := config.Load(configFile, bindings)
+		if err != nil {
+			return err
+		}
+		*cfg = *loaded
+
+		return nil
+	}
+
+	cmd.AddCommand(
+		newServeCmd(cfg),
+		newMigrateCmd(cfg),
+		newSeedCmd(cfg),
+		newPostsCmd(cfg),
+		newAuthorsCmd(cfg),
+	)
+
+	return cmd
+}
+
+// withContainer runs fn with the shared dependencies and shuts them down afterwards
+func withContainer(cfg *config.Config, fn func(c *container) error) (err error) {
+	c, err := newContainer(cfg)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+		defer cancel()
+
+		errShutdown := c.Shutdown(ctx)
+		if errShutdown != nil {
+			c.logger.WithError(errShutdown).Error("shutting down")
+		}
+	}()
+
+	return fn(c)
+}
+
+// cliActor is recorded as the actor of the audit events of mutations issued from the command line
+const cliActor = "cli"
+
+func commandContext(cmd *cobra.Command) context.Context {
+	return domain.NewContextWithRequestInfo(cmd.Context(), domain.RequestInfo{Actor: cliActor})
+}
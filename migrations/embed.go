@@ -0,0 +1,23 @@
+// Package migrations embeds the versioned schema migrations of every supported database kind.
+//
+// Each kind has its own directory named after the database.kind config value, holding
+// <version>_<name>.up.sql and <version>_<name>.down.sql files applied in version order.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// Source returns the migrations of the given database kind
+func Source(kind string) (fs.FS, error) {
+	if _, err := fs.Stat(files, kind); err != nil {
+		return nil, fmt.Errorf("no migrations for database kind %q", kind)
+	}
+
+	return fs.Sub(files, kind)
+}
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS `category`;
+
+DROP TABLE IF EXISTS `idempotency_key`;
+
+DROP TABLE IF EXISTS `rate_limit_counter`;
+
+DROP TABLE IF EXISTS `audit_event`;
+
+DROP TABLE IF EXISTS `author`;
+
+DROP TABLE IF EXISTS `post_category`;
+
+DROP TABLE IF EXISTS `post`;
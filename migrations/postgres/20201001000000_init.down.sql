@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS public.idempotency_key;
+
+DROP TABLE IF EXISTS public.rate_limit_counter;
+
+DROP TABLE IF EXISTS public.audit_event;
+
+DROP TABLE IF EXISTS public.post_category;
+
+DROP TABLE IF EXISTS public.post;
+
+DROP TABLE IF EXISTS public.category;
+
+DROP TABLE IF EXISTS public.author;
@@ -0,0 +1,74 @@
+-- Baseline schema, equivalent to post_psql.sql. Every statement is a no-op on a database
+-- bootstrapped from that dump, so existing installations can adopt the migrations as is.
+
+CREATE TABLE IF NOT EXISTS public.author (
+    id integer NOT NULL,
+    name character varying(200),
+    created_at timestamp(0) without time zone,
+    updated_at timestamp(0) without time zone
+);
+
+CREATE TABLE IF NOT EXISTS public.category (
+    id integer NOT NULL,
+    name character varying(45),
+    tag character varying(45),
+    created_at timestamp(0) without time zone,
+    updated_at timestamp(0) without time zone
+);
+
+CREATE TABLE IF NOT EXISTS public.post (
+    id integer NOT NULL,
+    title character varying(45),
+    content character varying(10485760),
+    author_id integer,
+    updated_at timestamp(0) without time zone,
+    created_at timestamp(0) without time zone
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS post_title_key ON public.post USING btree (title);
+
+CREATE TABLE IF NOT EXISTS public.post_category (
+    id integer NOT NULL,
+    post_id integer NOT NULL,
+    category_id integer NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS public.audit_event (
+    id bigserial PRIMARY KEY,
+    actor character varying(200) NOT NULL,
+    action character varying(20) NOT NULL,
+    entity character varying(45) NOT NULL,
+    entity_id bigint NOT NULL,
+    before_data jsonb,
+    after_data jsonb,
+    request_id character varying(100),
+    ip character varying(45),
+    created_at timestamp(6) without time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_entity_idx ON public.audit_event USING btree (entity, entity_id, id);
+
+CREATE OR REPLACE RULE audit_event_no_update AS ON UPDATE TO public.audit_event DO INSTEAD NOTHING;
+
+CREATE OR REPLACE RULE audit_event_no_delete AS ON DELETE TO public.audit_event DO INSTEAD NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.rate_limit_counter (
+    bucket character varying(255) NOT NULL,
+    window_start bigint NOT NULL,
+    hits bigint NOT NULL,
+    expires_at bigint NOT NULL,
+    PRIMARY KEY (bucket, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_counter_expires_at_idx ON public.rate_limit_counter USING btree (expires_at);
+
+CREATE TABLE IF NOT EXISTS public.idempotency_key (
+    idem_key character varying(64) PRIMARY KEY,
+    fingerprint character varying(64) NOT NULL,
+    status integer NOT NULL,
+    content_type character varying(100) NOT NULL,
+    body bytea,
+    expires_at bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON public.idempotency_key USING btree (expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE ONLY public.post
+    DROP CONSTRAINT IF EXISTS post_pkey;
+
+ALTER TABLE public.post ALTER COLUMN id DROP IDENTITY IF EXISTS;
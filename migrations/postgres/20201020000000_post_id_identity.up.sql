@@ -0,0 +1,20 @@
+-- post.id had neither a sequence nor a default, so an INSERT without an explicit id failed.
+-- The checks keep this safe on databases where the change was applied by hand.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'post' AND column_name = 'id' AND is_identity = 'YES'
+    ) THEN
+        ALTER TABLE public.post ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY;
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'post_pkey'
+    ) THEN
+        ALTER TABLE ONLY public.post ADD CONSTRAINT post_pkey PRIMARY KEY (id);
+    END IF;
+END
+$$;
+
+SELECT setval(pg_get_serial_sequence('public.post', 'id'), COALESCE((SELECT MAX(id) FROM public.post), 0) + 1, false);
@@ -0,0 +1,4 @@
+ALTER TABLE ONLY public.author
+    DROP CONSTRAINT IF EXISTS author_pkey;
+
+ALTER TABLE public.author ALTER COLUMN id DROP IDENTITY IF EXISTS;
@@ -0,0 +1,19 @@
+-- author.id has the same problem post.id had, see 20201020000000_post_id_identity.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'author' AND column_name = 'id' AND is_identity = 'YES'
+    ) THEN
+        ALTER TABLE public.author ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY;
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'author_pkey'
+    ) THEN
+        ALTER TABLE ONLY public.author ADD CONSTRAINT author_pkey PRIMARY KEY (id);
+    END IF;
+END
+$$;
+
+SELECT setval(pg_get_serial_sequence('public.author', 'id'), COALESCE((SELECT MAX(id) FROM public.author), 0) + 1, false);
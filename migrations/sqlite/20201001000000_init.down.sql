@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_event;
+DROP TABLE IF EXISTS post;
+DROP TABLE IF EXISTS author;
@@ -0,0 +1,46 @@
+-- Schema of the single file database used for local development and the integration tests.
+-- Times are stored as fixed width UTC text, see repository.SQLiteTime, so that they sort
+-- and compare in chronological order.
+
+CREATE TABLE IF NOT EXISTS author (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS post (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT UNIQUE,
+    content TEXT,
+    author_id INTEGER,
+    updated_at DATETIME,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS post_created_at_idx ON post (created_at);
+
+CREATE TABLE IF NOT EXISTS audit_event (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    before_data TEXT,
+    after_data TEXT,
+    request_id TEXT,
+    ip TEXT,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_entity_idx ON audit_event (entity, entity_id, id);
+
+CREATE TRIGGER IF NOT EXISTS audit_event_no_update BEFORE UPDATE ON audit_event
+BEGIN
+    SELECT RAISE(ABORT, 'audit_event is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_event_no_delete BEFORE DELETE ON audit_event
+BEGIN
+    SELECT RAISE(ABORT, 'audit_event is append-only');
+END;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS idempotency_key;
+DROP TABLE IF EXISTS rate_limit_counter;
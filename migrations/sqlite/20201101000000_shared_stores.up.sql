@@ -0,0 +1,23 @@
+-- Tables of the sql stores of the rate limiter and the idempotency middleware, as in postgres and mysql.
+-- Times are unix milliseconds, the stores compare them as numbers.
+
+CREATE TABLE IF NOT EXISTS rate_limit_counter (
+    bucket TEXT NOT NULL,
+    window_start INTEGER NOT NULL,
+    hits INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (bucket, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_counter_expires_at_idx ON rate_limit_counter (expires_at);
+
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    idem_key TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    content_type TEXT NOT NULL,
+    body BLOB,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
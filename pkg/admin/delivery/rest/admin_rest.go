@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ResponseError represent the response error struct
+type ResponseError struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+}
+
+// StatsProvider reports the connection pool statistics, *sql.DB implements it
+type StatsProvider interface {
+	Stats() sql.DBStats
+}
+
+// DBStats represent the connection pool statistics, durations are in milliseconds
+type DBStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDuration       int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// AdminHandler represent the rest handler for the operational endpoints
+type AdminHandler struct {
+	DB StatsProvider
+}
+
+// NewAdminHandler will initialize the admin endpoints under /admin.
+// When token is not empty every request needs it as a bearer token.
+func NewAdminHandler(app *fiber.App, token string, db StatsProvider) {
+	handler := &AdminHandler{
+		DB: db,
+	}
+
+	group := app.Group("/admin", requireToken(token))
+	group.Get("/db/stats", handler.DBStats)
+}
+
+// DBStats will return the statistics of the database connection pool
+func (ah *AdminHandler) DBStats(c *fiber.Ctx) error {
+	s := ah.DB.Stats()
+
+	c.Response().SetStatusCode(http.StatusOK)
+	return c.JSON(DBStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	})
+}
+
+func requireToken(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Next()
+		}
+
+		given := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Response().SetStatusCode(http.StatusUnauthorized)
+			return c.JSON(ResponseError{Error: http.StatusUnauthorized, Message: "invalid admin token"})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package rest_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	adminRest "github.com/ilmimris/poc-gofiber-clean-arch/pkg/admin/delivery/rest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubStats sql.DBStats
+
+func (s stubStats) Stats() sql.DBStats {
+	return sql.DBStats(s)
+}
+
+func TestDBStats(t *testing.T) {
+	e := fiber.New()
+	adminRest.NewAdminHandler(e, "", stubStats{
+		MaxOpenConnections: 10,
+		OpenConnections:    3,
+		InUse:              1,
+		Idle:               2,
+		WaitCount:          4,
+		WaitDuration:       1500 * time.Millisecond,
+	})
+
+	req, err := http.NewRequest("GET", "/admin/db/stats", nil)
+	assert.NoError(t, err)
+	rec, err := e.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+
+	var stats adminRest.DBStats
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, 10, stats.MaxOpenConnections)
+	assert.Equal(t, 3, stats.OpenConnections)
+	assert.Equal(t, int64(1500), stats.WaitDuration)
+}
+
+func TestDBStatsToken(t *testing.T) {
+	e := fiber.New()
+	adminRest.NewAdminHandler(e, "secret", stubStats{})
+
+	req, err := http.NewRequest("GET", "/admin/db/stats", nil)
+	assert.NoError(t, err)
+	rec, err := e.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, rec.StatusCode)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	rec, err = e.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+}
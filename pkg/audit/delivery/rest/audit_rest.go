@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// ResponseError represent the response error struct
+type ResponseError struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+}
+
+// AuditHandler represent the rest handler for audit
+type AuditHandler struct {
+	AUsecase domain.AuditUsecase
+	Logger   logrus.FieldLogger
+}
+
+// NewAuditHandler will initialize the audit resource endpoint
+func NewAuditHandler(app *fiber.App, a domain.AuditUsecase, logger logrus.FieldLogger) {
+	handler := &AuditHandler{
+		AUsecase: a,
+		Logger:   logger,
+	}
+
+	app.Get("/audit", handler.FetchAudit)
+}
+
+// FetchAudit will fetch the audit trail of an entity based on given params
+func (ah *AuditHandler) FetchAudit(c *fiber.Ctx) error {
+	entity := c.Query("entity")
+	id, _ := strconv.ParseInt(c.Query("id"), 10, 64)
+	num, _ := strconv.Atoi(c.Query("num"))
+	cursor := c.Query("cursor")
+	ctx := delivery.RequestContext(c)
+
+	listEvent, nextCursor, err := ah.AUsecase.Fetch(ctx, entity, id, cursor, int64(num))
+	if err != nil {
+		return ah.fail(ctx, c, err)
+	}
+
+	c.Response().SetStatusCode(http.StatusOK)
+	c.Response().Header.Set(`X-Cursor`, nextCursor)
+	return c.JSON(listEvent)
+}
+
+// fail logs err with the fields of the request and sends it as the response
+func (ah *AuditHandler) fail(ctx context.Context, c *fiber.Ctx, err error) error {
+	status := getStatusCode(err)
+	// drivers may report the deadline of the request with an error of their own
+	if ctx.Err() == context.DeadlineExceeded {
+		status = http.StatusGatewayTimeout
+	}
+
+	entry := logging.FromContext(ctx, ah.Logger).WithError(err)
+	if status >= http.StatusInternalServerError {
+		entry.Error("request failed")
+	} else {
+		entry.Info("request rejected")
+	}
+
+	c.Response().SetStatusCode(status)
+	return c.JSON(ResponseError{Error: status, Message: err.Error()})
+}
+
+func getStatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	switch err {
+	case domain.ErrInternalServerError:
+		return http.StatusInternalServerError
+	case domain.ErrNotFound:
+		return http.StatusNotFound
+	case domain.ErrBadParamInput:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,60 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bxcodec/faker"
+	"github.com/gofiber/fiber/v2"
+	auditRest "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/delivery/rest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	mocks "github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchAudit(t *testing.T) {
+	var mockEvent domain.AuditEvent
+	err := faker.FakeData(&mockEvent)
+	assert.NoError(t, err)
+	mockEvent.Before = nil
+	mockEvent.After = []byte(`{"id":12}`)
+	mockUCase := new(mocks.AuditUsecase)
+	mockUCase.On("Fetch", mock.Anything, "post", int64(12), "2", int64(1)).
+		Return([]domain.AuditEvent{mockEvent}, "10", nil)
+
+	e := fiber.New()
+	req, err := http.NewRequest("GET", "/audit?entity=post&id=12&num=1&cursor=2", strings.NewReader(""))
+	assert.NoError(t, err)
+
+	auditRest.NewAuditHandler(e, mockUCase, logrus.New())
+	rec, err := e.Test(req, -1)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, "10", rec.Header.Get("X-Cursor"))
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestFetchAuditBadParam(t *testing.T) {
+	mockUCase := new(mocks.AuditUsecase)
+	mockUCase.On("Fetch", mock.Anything, "", int64(0), "", int64(0)).
+		Return(nil, "", domain.ErrBadParamInput)
+
+	e := fiber.New()
+	req, err := http.NewRequest("GET", "/audit", strings.NewReader(""))
+	assert.NoError(t, err)
+
+	auditRest.NewAuditHandler(e, mockUCase, logrus.New())
+	rec, err := e.Test(req, -1)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, rec.StatusCode)
+	mockUCase.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type memoryAuditRepo struct {
+	mu sync.RWMutex
+	// events is append-only and ordered by id, like the audit_event table
+	events []domain.AuditEvent
+}
+
+// NewMemoryAuditRepository will create an implementation of audit repository keeping the events in memory
+func NewMemoryAuditRepository() domain.AuditRepository {
+	return &memoryAuditRepo{}
+}
+
+func (m *memoryAuditRepo) Store(ctx context.Context, entry *domain.AuditEvent) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = int64(len(m.events) + 1)
+
+	// the strings may point into a buffer the caller reuses, such as the request headers
+	event := *entry
+	event.Actor = copyString(entry.Actor)
+	event.RequestID = copyString(entry.RequestID)
+	event.IP = copyString(entry.IP)
+	event.Before = append([]byte(nil), entry.Before...)
+	event.After = append([]byte(nil), entry.After...)
+	m.events = append(m.events, event)
+	return
+}
+
+func copyString(s string) string {
+	return string([]byte(s))
+}
+
+func (m *memoryAuditRepo) Fetch(ctx context.Context, entity string, entityID int64, cursor string, num int64) (res []domain.AuditEvent, nextCursor string, err error) {
+	decodedCursor, err := repository.DecodeIDCursor(cursor)
+	if err != nil && cursor != "" {
+		return nil, "", domain.ErrBadParamInput
+	}
+	err = nil
+
+	res = make([]domain.AuditEvent, 0)
+	if num <= 0 {
+		return
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.events {
+		if int64(len(res)) == num {
+			break
+		}
+		if e.Entity == entity && e.EntityID == entityID && e.ID > decodedCursor {
+			res = append(res, e)
+		}
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodeIDCursor(res[len(res)-1].ID)
+	}
+
+	return
+}
@@ -0,0 +1,54 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	auditRepo "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/repository/memory"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepositorySuite(t *testing.T) {
+	repositorytest.RunAuditRepositorySuite(t, func(t *testing.T) domain.AuditRepository {
+		return auditRepo.NewMemoryAuditRepository()
+	})
+}
+
+func TestFetchNothing(t *testing.T) {
+	a := auditRepo.NewMemoryAuditRepository()
+	require.NoError(t, a.Store(context.TODO(), &domain.AuditEvent{Entity: domain.AuditEntityPost, EntityID: 1}))
+
+	for _, num := range []int64{0, -1} {
+		list, next, err := a.Fetch(context.TODO(), domain.AuditEntityPost, 1, "", num)
+
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		assert.Empty(t, next)
+	}
+}
+
+func TestStoreCopiesStrings(t *testing.T) {
+	a := auditRepo.NewMemoryAuditRepository()
+
+	// the request headers back the strings of the request info, fasthttp reuses them
+	header := []byte("user-1|req-1|10.0.0.1")
+	require.NoError(t, a.Store(context.TODO(), &domain.AuditEvent{
+		Actor:     utils.UnsafeString(header[:6]),
+		RequestID: utils.UnsafeString(header[7:12]),
+		IP:        utils.UnsafeString(header[13:]),
+		Entity:    domain.AuditEntityPost,
+		EntityID:  1,
+	}))
+	copy(header, "user-2|req-2|10.0.0.2")
+
+	list, _, err := a.Fetch(context.TODO(), domain.AuditEntityPost, 1, "", 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "user-1", list[0].Actor)
+	assert.Equal(t, "req-1", list[0].RequestID)
+	assert.Equal(t, "10.0.0.1", list[0].IP)
+}
@@ -0,0 +1,119 @@
+package sqlrepo
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type sqlAuditRepo struct {
+	Router  *repository.Router
+	Dialect repository.Dialect
+	Logger  logrus.FieldLogger
+	Stmts   *repository.StmtCache
+}
+
+// NewSQLAuditRepository will create an implementation of audit repository for the sql engine spoken by dialect.
+// Reads go to the database router picks for them. The repository prepares its statements once,
+// it implements io.Closer to release them.
+func NewSQLAuditRepository(router *repository.Router, dialect repository.Dialect, logger logrus.FieldLogger) domain.AuditRepository {
+	return &sqlAuditRepo{
+		Router:  router,
+		Dialect: dialect,
+		Logger:  logger,
+		Stmts:   repository.NewStmtCache(router),
+	}
+}
+
+// Close closes the prepared statements
+func (p *sqlAuditRepo) Close() error {
+	return p.Stmts.Close()
+}
+
+func (p *sqlAuditRepo) Store(ctx context.Context, entry *domain.AuditEvent) (err error) {
+	query := `INSERT INTO ` + p.Dialect.Table("audit_event") + ` (actor, action, entity, entity_id, before_data, after_data, request_id, ip, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	entry.ID, err = repository.Insert(ctx, p.Stmts, p.Dialect, query,
+		entry.Actor, entry.Action, entry.Entity, entry.EntityID,
+		nullableJSON(entry.Before), nullableJSON(entry.After),
+		entry.RequestID, entry.IP, p.Dialect.Time(entry.CreatedAt))
+	return
+}
+
+func (p *sqlAuditRepo) Fetch(ctx context.Context, entity string, entityID int64, cursor string, num int64) (res []domain.AuditEvent, nextCursor string, err error) {
+	query := `SELECT id, actor, action, entity, entity_id, before_data, after_data, request_id, ip, created_at
+				FROM ` + p.Dialect.Table("audit_event") + `
+				WHERE entity = ? AND entity_id = ? AND id > ?
+				ORDER BY id
+				LIMIT ?`
+
+	decodedCursor, err := repository.DecodeIDCursor(cursor)
+	if err != nil && cursor != "" {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	statement, err := p.Stmts.PrepareRead(ctx, repository.Rebind(p.Dialect, query))
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := statement.QueryContext(ctx, entity, entityID, decodedCursor, num)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logging.FromContext(ctx, p.Logger).WithError(errRow).Error("closing rows")
+		}
+	}()
+
+	res = make([]domain.AuditEvent, 0)
+	for rows.Next() {
+		t := domain.AuditEvent{}
+		var before, after []byte
+
+		err = rows.Scan(
+			&t.ID,
+			&t.Actor,
+			&t.Action,
+			&t.Entity,
+			&t.EntityID,
+			&before,
+			&after,
+			&t.RequestID,
+			&t.IP,
+			&t.CreatedAt,
+		)
+
+		if err != nil {
+			logging.FromContext(ctx, p.Logger).WithError(err).Error("scanning row")
+			return nil, "", err
+		}
+
+		t.Before = before
+		t.After = after
+		res = append(res, t)
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodeIDCursor(res[len(res)-1].ID)
+	}
+
+	return
+}
+
+// nullableJSON stores empty documents as NULL instead of an invalid empty string
+func nullableJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return string(raw)
+}
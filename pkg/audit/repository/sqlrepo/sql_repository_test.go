@@ -0,0 +1,117 @@
+package sqlrepo_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	auditRepo "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/repository/sqlrepo"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var dialects = []struct {
+	kind   string
+	insert string
+	fetch  string
+}{
+	{
+		kind:   "postgres",
+		insert: `INSERT INTO public."audit_event" (actor, action, entity, entity_id, before_data, after_data, request_id, ip, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		fetch:  `SELECT id, actor, action, entity, entity_id, before_data, after_data, request_id, ip, created_at FROM public."audit_event" WHERE entity = $1 AND entity_id = $2 AND id > $3 ORDER BY id LIMIT $4`,
+	},
+	{
+		kind:   "mysql",
+		insert: "INSERT INTO `audit_event` (actor, action, entity, entity_id, before_data, after_data, request_id, ip, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		fetch:  "SELECT id, actor, action, entity, entity_id, before_data, after_data, request_id, ip, created_at FROM `audit_event` WHERE entity = ? AND entity_id = ? AND id > ? ORDER BY id LIMIT ?",
+	},
+}
+
+func TestStore(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d, err := repository.DialectFor(dc.kind)
+			require.NoError(t, err)
+
+			now := time.Now()
+			event := &domain.AuditEvent{
+				Actor:     "editor",
+				Action:    domain.AuditActionCreate,
+				Entity:    domain.AuditEntityPost,
+				EntityID:  12,
+				After:     json.RawMessage(`{"id":12}`),
+				RequestID: "req-1",
+				IP:        "10.0.0.1",
+				CreatedAt: now,
+			}
+
+			prep := mock.ExpectPrepare(regexp.QuoteMeta(dc.insert))
+			if d.Returning() {
+				prep.ExpectQuery().
+					WithArgs(event.Actor, event.Action, event.Entity, event.EntityID, nil, `{"id":12}`, event.RequestID, event.IP, now).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			} else {
+				prep.ExpectExec().
+					WithArgs(event.Actor, event.Action, event.Entity, event.EntityID, nil, `{"id":12}`, event.RequestID, event.IP, now).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+			}
+
+			a := auditRepo.NewSQLAuditRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			err = a.Store(context.TODO(), event)
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(7), event.ID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d, err := repository.DialectFor(dc.kind)
+			require.NoError(t, err)
+
+			rows := sqlmock.NewRows([]string{"id", "actor", "action", "entity", "entity_id", "before_data", "after_data", "request_id", "ip", "created_at"}).
+				AddRow(1, "editor", "create", "post", 12, nil, []byte(`{"id":12}`), "req-1", "10.0.0.1", time.Now()).
+				AddRow(2, "editor", "delete", "post", 12, []byte(`{"id":12}`), nil, "req-2", "10.0.0.1", time.Now())
+			mock.ExpectPrepare(regexp.QuoteMeta(dc.fetch)).ExpectQuery().WithArgs("post", 12, 0, 2).WillReturnRows(rows)
+
+			a := auditRepo.NewSQLAuditRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			list, nextCursor, err := a.Fetch(context.TODO(), "post", 12, "", 2)
+
+			assert.NoError(t, err)
+			assert.Len(t, list, 2)
+			assert.Equal(t, repository.EncodeIDCursor(2), nextCursor)
+			assert.Nil(t, list[0].Before)
+			assert.JSONEq(t, `{"id":12}`, string(list[0].After))
+
+			_, _, err = a.Fetch(context.TODO(), "post", 12, "not-a-cursor", 2)
+			assert.Equal(t, domain.ErrBadParamInput, err)
+		})
+	}
+}
+
+func TestAuditRepositorySuite(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		kind := kind
+		t.Run(kind, func(t *testing.T) {
+			repositorytest.RunAuditRepositorySuite(t, func(t *testing.T) domain.AuditRepository {
+				db, d := repositorytest.OpenDatabase(t, kind)
+				return auditRepo.NewSQLAuditRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			})
+		})
+	}
+}
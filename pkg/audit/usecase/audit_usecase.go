@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/deadline"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type auditUsecase struct {
+	auditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+}
+
+// NewAuditUsecase will create new an auditUsecase object representation of domain.AuditUsecase interface
+func NewAuditUsecase(ar domain.AuditRepository, timeout time.Duration) domain.AuditUsecase {
+	return &auditUsecase{
+		auditRepo:      ar,
+		contextTimeout: timeout,
+	}
+}
+
+func (a *auditUsecase) Fetch(c context.Context, entity string, entityID int64, cursor string, num int64) (res []domain.AuditEvent, nextCursor string, err error) {
+	if entity == "" || entityID <= 0 || num < 0 {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	if num == 0 {
+		num = 10
+	}
+
+	ctx, cancel := deadline.WithDefault(c, a.contextTimeout)
+	defer cancel()
+
+	return a.auditRepo.Fetch(ctx, entity, entityID, cursor, num)
+}
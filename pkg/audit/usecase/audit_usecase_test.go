@@ -0,0 +1,69 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ucase "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/usecase"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFetch(t *testing.T) {
+	mockAuditRepo := new(mocks.AuditRepository)
+	mockEvent := domain.AuditEvent{
+		ID:       1,
+		Actor:    "editor",
+		Action:   domain.AuditActionCreate,
+		Entity:   domain.AuditEntityPost,
+		EntityID: 12,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockAuditRepo.On("Fetch", mock.Anything, "post", int64(12), "", int64(10)).
+			Return([]domain.AuditEvent{mockEvent}, "next-cursor", nil).Once()
+		u := ucase.NewAuditUsecase(mockAuditRepo, time.Second*2)
+
+		list, nextCursor, err := u.Fetch(context.TODO(), "post", 12, "", 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "next-cursor", nextCursor)
+		assert.Len(t, list, 1)
+		mockAuditRepo.AssertExpectations(t)
+	})
+
+	t.Run("missing-entity", func(t *testing.T) {
+		u := ucase.NewAuditUsecase(mockAuditRepo, time.Second*2)
+
+		_, _, err := u.Fetch(context.TODO(), "", 12, "", 1)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+		mockAuditRepo.AssertExpectations(t)
+	})
+
+	t.Run("negative-num", func(t *testing.T) {
+		u := ucase.NewAuditUsecase(mockAuditRepo, time.Second*2)
+
+		_, _, err := u.Fetch(context.TODO(), "post", 12, "", -1)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+		mockAuditRepo.AssertExpectations(t)
+	})
+
+	t.Run("error-failed", func(t *testing.T) {
+		mockAuditRepo.On("Fetch", mock.Anything, "post", int64(12), "", int64(1)).
+			Return(nil, "", errors.New("Unexpected Error")).Once()
+		u := ucase.NewAuditUsecase(mockAuditRepo, time.Second*2)
+
+		list, nextCursor, err := u.Fetch(context.TODO(), "post", 12, "", 1)
+
+		assert.Error(t, err)
+		assert.Empty(t, nextCursor)
+		assert.Len(t, list, 0)
+		mockAuditRepo.AssertExpectations(t)
+	})
+}
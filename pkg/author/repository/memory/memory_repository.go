@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type memoryAuthorRepo struct {
+	mu      sync.RWMutex
+	lastID  int64
+	authors map[int64]domain.Author
+}
+
+// NewMemoryAuthorRepository will create an implementation of author repository keeping the authors in memory
+func NewMemoryAuthorRepository() domain.AuthorRepository {
+	return &memoryAuthorRepo{
+		authors: make(map[int64]domain.Author),
+	}
+}
+
+func (m *memoryAuthorRepo) GetByID(ctx context.Context, id int64) (res domain.Author, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res, ok := m.authors[id]
+	if !ok {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (m *memoryAuthorRepo) Store(ctx context.Context, a *domain.Author) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	a.ID = m.lastID
+
+	m.authors[a.ID] = *a
+	return
+}
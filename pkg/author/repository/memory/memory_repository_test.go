@@ -0,0 +1,16 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	authorRepo "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/memory"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+func TestAuthorRepositorySuite(t *testing.T) {
+	repositorytest.RunAuthorRepositorySuite(t, func(t *testing.T) domain.AuthorRepository {
+		return authorRepo.NewMemoryAuthorRepository()
+	}, time.Nanosecond)
+}
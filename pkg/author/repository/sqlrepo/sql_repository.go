@@ -0,0 +1,68 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type sqlAuthorRepo struct {
+	Router  *repository.Router
+	Dialect repository.Dialect
+	Stmts   *repository.StmtCache
+}
+
+// NewSQLAuthorRepository will create an implementation of author repository for the sql engine spoken by dialect.
+// Reads go to the database router picks for them. The repository prepares its statements once,
+// it implements io.Closer to release them.
+func NewSQLAuthorRepository(router *repository.Router, dialect repository.Dialect) domain.AuthorRepository {
+	return &sqlAuthorRepo{
+		Router:  router,
+		Dialect: dialect,
+		Stmts:   repository.NewStmtCache(router),
+	}
+}
+
+// Close closes the prepared statements
+func (p *sqlAuthorRepo) Close() error {
+	return p.Stmts.Close()
+}
+
+func (p *sqlAuthorRepo) getOne(ctx context.Context, query string, args ...interface{}) (res domain.Author, err error) {
+	statement, err := p.Stmts.PrepareRead(ctx, repository.Rebind(p.Dialect, query))
+
+	// if error
+	if err != nil {
+		return domain.Author{}, err
+	}
+
+	row := statement.QueryRowContext(ctx, args...)
+	res = domain.Author{}
+
+	err = row.Scan(
+		&res.ID,
+		&res.Name,
+		&res.CreatedAt,
+		&res.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return domain.Author{}, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *sqlAuthorRepo) GetByID(ctx context.Context, id int64) (domain.Author, error) {
+	query := `SELECT id, name, created_at, updated_at FROM ` + p.Dialect.Table("author") + ` WHERE id=?`
+	return p.getOne(ctx, query, id)
+}
+
+func (p *sqlAuthorRepo) Store(ctx context.Context, a *domain.Author) (err error) {
+	query := `INSERT INTO ` + p.Dialect.Table("author") + ` (name, created_at, updated_at) VALUES (?, ?, ?)`
+
+	a.ID, err = repository.Insert(ctx, p.Stmts, p.Dialect, query,
+		a.Name, p.Dialect.Time(a.CreatedAt), p.Dialect.Time(a.UpdatedAt))
+	return
+}
@@ -0,0 +1,99 @@
+package sqlrepo_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	authorRepo "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/sqlrepo"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var dialects = []struct {
+	kind   string
+	get    string
+	insert string
+}{
+	{
+		kind:   "postgres",
+		get:    `SELECT id, name, created_at, updated_at FROM public."author" WHERE id=$1`,
+		insert: `INSERT INTO public."author" (name, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id`,
+	},
+	{
+		kind:   "mysql",
+		get:    "SELECT id, name, created_at, updated_at FROM `author` WHERE id=?",
+		insert: "INSERT INTO `author` (name, created_at, updated_at) VALUES (?, ?, ?)",
+	},
+}
+
+func TestGetByID(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d, err := repository.DialectFor(dc.kind)
+			require.NoError(t, err)
+
+			rows := sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at"}).
+				AddRow(1, "Dummy User", time.Now(), time.Now())
+			mock.ExpectPrepare(regexp.QuoteMeta(dc.get)).ExpectQuery().WithArgs(1).WillReturnRows(rows)
+
+			a := authorRepo.NewSQLAuthorRepository(repository.NewRouter(db, nil, 0, nil), d)
+			author, err := a.GetByID(context.TODO(), 1)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "Dummy User", author.Name)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d, err := repository.DialectFor(dc.kind)
+			require.NoError(t, err)
+
+			now := time.Now()
+			author := &domain.Author{Name: "Dummy User", CreatedAt: now, UpdatedAt: now}
+
+			prep := mock.ExpectPrepare(regexp.QuoteMeta(dc.insert))
+			if d.Returning() {
+				prep.ExpectQuery().WithArgs(author.Name, now, now).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			} else {
+				prep.ExpectExec().WithArgs(author.Name, now, now).WillReturnResult(sqlmock.NewResult(7, 1))
+			}
+
+			a := authorRepo.NewSQLAuthorRepository(repository.NewRouter(db, nil, 0, nil), d)
+			err = a.Store(context.TODO(), author)
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(7), author.ID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthorRepositorySuite(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		kind := kind
+		t.Run(kind, func(t *testing.T) {
+			d, err := repository.DialectFor(kind)
+			require.NoError(t, err)
+
+			repositorytest.RunAuthorRepositorySuite(t, func(t *testing.T) domain.AuthorRepository {
+				db, d := repositorytest.OpenDatabase(t, kind)
+				return authorRepo.NewSQLAuthorRepository(repository.NewRouter(db, nil, 0, nil), d)
+			}, d.Precision())
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package breaker
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/cache"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type authorRepository struct {
+	next  domain.AuthorRepository
+	b     *Breaker
+	stale *cache.LRU
+}
+
+// NewAuthorRepository will wrap a domain.AuthorRepository, running every call through b. With a
+// stale cache, the authors read are kept and served again while b is open.
+func NewAuthorRepository(next domain.AuthorRepository, b *Breaker, stale *cache.LRU) domain.AuthorRepository {
+	return &authorRepository{
+		next:  next,
+		b:     b,
+		stale: stale,
+	}
+}
+
+func (a *authorRepository) GetByID(ctx context.Context, id int64) (res domain.Author, err error) {
+	err = a.b.Do(ctx, func() (err error) {
+		res, err = a.next.GetByID(ctx, id)
+		return
+	})
+	if a.stale == nil {
+		return
+	}
+
+	key := "author:id:" + strconv.FormatInt(id, 10)
+	switch {
+	case err == nil:
+		a.stale.Set(key, res)
+	case unavailable(err):
+		if kept, ok := a.stale.Get(key); ok {
+			return kept.(domain.Author), nil
+		}
+	}
+
+	return
+}
+
+func (a *authorRepository) Store(ctx context.Context, author *domain.Author) error {
+	return a.b.Do(ctx, func() error {
+		return a.next.Store(ctx, author)
+	})
+}
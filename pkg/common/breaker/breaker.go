@@ -0,0 +1,198 @@
+// Package breaker stops sending calls to a failing database for a while, so that the requests fail
+// fast instead of each waiting out its timeout. The calls are guarded by decorators wrapping the
+// domain interfaces.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// Config represent when a breaker opens and for how long
+type Config struct {
+	// Window is the period the calls are counted over, the counts restart with every window
+	Window time.Duration
+	// MinCalls is how many calls a window needs before its error rate can open the breaker
+	MinCalls int
+	// ErrorRate is the fraction of failed calls of a window opening the breaker, between 0 and 1
+	ErrorRate float64
+	// OpenTimeout is how long the breaker turns the calls away before letting one through to probe
+	OpenTimeout time.Duration
+}
+
+type state int
+
+const (
+	closed state = iota
+	open
+	halfOpen
+)
+
+func (s state) String() string {
+	switch s {
+	case open:
+		return "open"
+	case halfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker counts the outcome of the calls it runs. Once the error rate of a window reaches
+// Config.ErrorRate it opens and fails every call with a domain.UnavailableError for
+// Config.OpenTimeout. Then a single call probes the database: its success closes the breaker,
+// its failure, a panic included, opens it again.
+type Breaker struct {
+	Name   string
+	Config Config
+	Logger logrus.FieldLogger
+
+	mu          sync.Mutex
+	state       state
+	windowStart time.Time
+	calls       int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+// NewBreaker will create a closed breaker, name tells it apart in the logs
+func NewBreaker(name string, cfg Config, logger logrus.FieldLogger) *Breaker {
+	return &Breaker{
+		Name:        name,
+		Config:      cfg,
+		Logger:      logger,
+		windowStart: time.Now(),
+	}
+}
+
+// errPanicked is the outcome recorded for a call that panicked, the panic itself goes on up
+var errPanicked = errors.New("call panicked")
+
+// Do runs fn unless the breaker is open, and counts its outcome. ctx is that of the call: once
+// it is done, the outcome tells nothing about the database and is not counted.
+func (b *Breaker) Do(ctx context.Context, fn func() error) (err error) {
+	err = b.allow()
+	if err != nil {
+		return err
+	}
+
+	returned := false
+	defer func() {
+		if !returned {
+			b.record(ctx, errPanicked)
+		}
+	}()
+
+	err = fn()
+	returned = true
+	b.record(ctx, err)
+	return err
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case open:
+		reopen := b.openedAt.Add(b.Config.OpenTimeout)
+		if now.Before(reopen) {
+			return &domain.UnavailableError{RetryAfter: reopen.Sub(now)}
+		}
+		b.transition(halfOpen, nil)
+		b.probing = true
+	case halfOpen:
+		if b.probing {
+			return &domain.UnavailableError{RetryAfter: b.Config.OpenTimeout}
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+func (b *Breaker) record(ctx context.Context, err error) {
+	// the client went away or ran out of time, the call tells nothing about the database
+	if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+		b.mu.Lock()
+		if b.state == halfOpen {
+			b.probing = false
+		}
+		b.mu.Unlock()
+		return
+	}
+
+	failed := failure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case halfOpen:
+		b.probing = false
+		if failed {
+			b.openedAt = now
+			b.transition(open, err)
+		} else {
+			b.resetWindow(now)
+			b.transition(closed, nil)
+		}
+	case closed:
+		if now.Sub(b.windowStart) >= b.Config.Window {
+			b.resetWindow(now)
+		}
+
+		b.calls++
+		if failed {
+			b.failures++
+		}
+
+		if b.calls >= b.Config.MinCalls && float64(b.failures) >= b.Config.ErrorRate*float64(b.calls) {
+			b.openedAt = now
+			b.transition(open, err)
+		}
+	}
+}
+
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.calls = 0
+	b.failures = 0
+}
+
+func (b *Breaker) transition(to state, err error) {
+	entry := b.Logger.WithField("breaker", b.Name).WithField("state", to.String())
+	switch to {
+	case open:
+		entry.WithError(err).WithField("failures", b.failures).WithField("calls", b.calls).Warn("opening circuit breaker")
+	default:
+		entry.Info("circuit breaker state changed")
+	}
+
+	b.state = to
+}
+
+// failure reports whether err tells that the database is failing. The domain errors are
+// answers of a working database.
+func failure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, answer := range []error{domain.ErrNotFound, domain.ErrConflict, domain.ErrBadParamInput} {
+		if errors.Is(err, answer) {
+			return false
+		}
+	}
+
+	return true
+}
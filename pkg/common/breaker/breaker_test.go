@@ -0,0 +1,232 @@
+package breaker_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/breaker"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/cache"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var cfg = breaker.Config{Window: time.Minute, MinCalls: 4, ErrorRate: 0.5, OpenTimeout: 20 * time.Millisecond}
+
+func newBreaker() *breaker.Breaker {
+	return breaker.NewBreaker("test", cfg, logrus.New())
+}
+
+func fail(err error) func() error {
+	return func() error { return err }
+}
+
+func succeed() error {
+	return nil
+}
+
+func TestBreakerOpens(t *testing.T) {
+	b := newBreaker()
+
+	require.NoError(t, b.Do(context.TODO(), succeed))
+	require.NoError(t, b.Do(context.TODO(), succeed))
+	assert.Equal(t, driver.ErrBadConn, b.Do(context.TODO(), fail(driver.ErrBadConn)))
+	assert.Equal(t, driver.ErrBadConn, b.Do(context.TODO(), fail(driver.ErrBadConn)))
+
+	calls := 0
+	err := b.Do(context.TODO(), func() error {
+		calls++
+		return nil
+	})
+
+	assert.Equal(t, 0, calls)
+	assert.True(t, errors.Is(err, domain.ErrUnavailable))
+	var unavailable *domain.UnavailableError
+	require.True(t, errors.As(err, &unavailable))
+	assert.True(t, unavailable.RetryAfter > 0 && unavailable.RetryAfter <= cfg.OpenTimeout)
+}
+
+func TestBreakerIgnoresDomainErrors(t *testing.T) {
+	b := newBreaker()
+
+	for _, err := range []error{domain.ErrNotFound, domain.ErrConflict, domain.ErrBadParamInput, context.Canceled} {
+		assert.Equal(t, err, b.Do(context.TODO(), fail(err)))
+	}
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, domain.ErrNotFound, b.Do(context.TODO(), fail(domain.ErrNotFound)))
+	}
+
+	assert.NoError(t, b.Do(context.TODO(), succeed))
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	trip := func(b *breaker.Breaker) {
+		for i := 0; i < cfg.MinCalls; i++ {
+			b.Do(context.TODO(), fail(driver.ErrBadConn))
+		}
+		require.True(t, errors.Is(b.Do(context.TODO(), succeed), domain.ErrUnavailable))
+		time.Sleep(cfg.OpenTimeout)
+	}
+
+	t.Run("probe succeeds", func(t *testing.T) {
+		b := newBreaker()
+		trip(b)
+
+		assert.NoError(t, b.Do(context.TODO(), succeed))
+		assert.NoError(t, b.Do(context.TODO(), succeed))
+	})
+
+	t.Run("probe fails", func(t *testing.T) {
+		b := newBreaker()
+		trip(b)
+
+		assert.Equal(t, driver.ErrBadConn, b.Do(context.TODO(), fail(driver.ErrBadConn)))
+		assert.True(t, errors.Is(b.Do(context.TODO(), succeed), domain.ErrUnavailable))
+	})
+}
+
+func TestBreakerIgnoresCallerDeadline(t *testing.T) {
+	b := newBreaker()
+
+	// the request ran out of time, not the database
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	for i := 0; i < cfg.MinCalls; i++ {
+		assert.Equal(t, context.DeadlineExceeded, b.Do(ctx, fail(context.DeadlineExceeded)))
+	}
+	assert.NoError(t, b.Do(context.TODO(), succeed))
+
+	// a timeout of the database call itself, with time left to the request, is a failure
+	for i := 0; i < cfg.MinCalls; i++ {
+		b.Do(context.TODO(), fail(context.DeadlineExceeded))
+	}
+	assert.True(t, errors.Is(b.Do(context.TODO(), succeed), domain.ErrUnavailable))
+}
+
+func TestBreakerProbePanics(t *testing.T) {
+	b := newBreaker()
+	for i := 0; i < cfg.MinCalls; i++ {
+		b.Do(context.TODO(), fail(driver.ErrBadConn))
+	}
+	time.Sleep(cfg.OpenTimeout)
+
+	assert.Panics(t, func() {
+		b.Do(context.TODO(), func() error { panic("boom") })
+	})
+
+	// the panicking probe opened the breaker again, the next probe gets through
+	assert.True(t, errors.Is(b.Do(context.TODO(), succeed), domain.ErrUnavailable))
+	time.Sleep(cfg.OpenTimeout)
+	assert.NoError(t, b.Do(context.TODO(), succeed))
+	assert.NoError(t, b.Do(context.TODO(), succeed))
+}
+
+func TestPostRepositoryServesStaleReads(t *testing.T) {
+	post := domain.Post{ID: 1, Title: "Title"}
+	mockRepo := new(mocks.PostRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(post, nil).Once()
+	mockRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(domain.Post{}, driver.ErrBadConn)
+
+	repo := breaker.NewPostRepository(mockRepo, newBreaker(), cache.NewLRU(10))
+
+	res, err := repo.GetByID(context.TODO(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, post, res)
+
+	for i := 0; i < cfg.MinCalls; i++ {
+		repo.GetByID(context.TODO(), 2)
+	}
+
+	t.Run("kept", func(t *testing.T) {
+		res, err := repo.GetByID(context.TODO(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, post, res)
+	})
+
+	t.Run("never read", func(t *testing.T) {
+		_, err := repo.GetByID(context.TODO(), 3)
+
+		assert.True(t, errors.Is(err, domain.ErrUnavailable))
+	})
+
+	// the breaker opened on the MinCalls-th call, the calls after it never reached the repository
+	mockRepo.AssertNumberOfCalls(t, "GetByID", cfg.MinCalls)
+}
+
+func TestPostRepositoryForgetsWrittenPosts(t *testing.T) {
+	post := domain.Post{ID: 1, Title: "Title"}
+	cases := []struct {
+		name  string
+		write func(repo domain.PostRepository) error
+		// servedPost and servedPage tell whether the post and the page read before the write are
+		// served once the breaker is open
+		servedPost bool
+		servedPage bool
+	}{
+		{name: "no write", write: func(domain.PostRepository) error { return nil }, servedPost: true, servedPage: true},
+		{name: "rename", write: func(repo domain.PostRepository) error {
+			renamed := post
+			renamed.Title = "Renamed"
+			return repo.Update(context.TODO(), &renamed)
+		}},
+		{name: "delete", write: func(repo domain.PostRepository) error {
+			return repo.Delete(context.TODO(), post.ID)
+		}},
+		{name: "another post stored", write: func(repo domain.PostRepository) error {
+			return repo.Store(context.TODO(), &domain.Post{Title: "Another"})
+		}, servedPost: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.PostRepository)
+			mockRepo.On("GetByTitle", mock.Anything, "Title").Return(post, nil).Once()
+			mockRepo.On("Fetch", mock.Anything, "", int64(10)).Return([]domain.Post{post}, "", nil).Once()
+			mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(nil)
+			mockRepo.On("Delete", mock.Anything, post.ID).Return(nil)
+			mockRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(nil)
+			mockRepo.On("GetByID", mock.Anything, int64(2)).Return(domain.Post{}, driver.ErrBadConn)
+
+			repo := breaker.NewPostRepository(mockRepo, newBreaker(), cache.NewLRU(10))
+
+			_, err := repo.GetByTitle(context.TODO(), "Title")
+			require.NoError(t, err)
+			_, _, err = repo.Fetch(context.TODO(), "", 10)
+			require.NoError(t, err)
+			require.NoError(t, tc.write(repo))
+
+			for i := 0; i < cfg.MinCalls; i++ {
+				repo.GetByID(context.TODO(), 2)
+			}
+
+			byTitle, errByTitle := repo.GetByTitle(context.TODO(), "Title")
+			byID, errByID := repo.GetByID(context.TODO(), post.ID)
+			list, _, errFetch := repo.Fetch(context.TODO(), "", 10)
+
+			if tc.servedPost {
+				assert.NoError(t, errByTitle)
+				assert.Equal(t, post, byTitle)
+				assert.NoError(t, errByID)
+				assert.Equal(t, post, byID)
+			} else {
+				assert.True(t, errors.Is(errByTitle, domain.ErrUnavailable))
+				assert.True(t, errors.Is(errByID, domain.ErrUnavailable))
+			}
+
+			if tc.servedPage {
+				assert.NoError(t, errFetch)
+				assert.Equal(t, []domain.Post{post}, list)
+			} else {
+				assert.True(t, errors.Is(errFetch, domain.ErrUnavailable))
+			}
+		})
+	}
+}
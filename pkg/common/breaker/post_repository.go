@@ -0,0 +1,189 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/cache"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type postRepository struct {
+	next  domain.PostRepository
+	b     *Breaker
+	stale *cache.LRU
+
+	// writes counts the successful writes. A read started before the last write is not kept, a
+	// page kept before it is not served.
+	mu     sync.Mutex
+	writes uint64
+}
+
+// NewPostRepository will wrap a domain.PostRepository, running every call through b. With a
+// stale cache, the results of the reads are kept and served again while b is open. A post is
+// kept under its id, its title only points at the id, so that updating or deleting the post
+// forgets it under every title it was read by. Every write forgets the pages.
+func NewPostRepository(next domain.PostRepository, b *Breaker, stale *cache.LRU) domain.PostRepository {
+	return &postRepository{
+		next:  next,
+		b:     b,
+		stale: stale,
+	}
+}
+
+func postIDKey(id int64) string {
+	return "post:id:" + strconv.FormatInt(id, 10)
+}
+
+func postTitleKey(title string) string {
+	return "post:title:" + title
+}
+
+// page is a result of Fetch kept in the stale cache
+type page struct {
+	posts      []domain.Post
+	nextCursor string
+	writes     uint64
+}
+
+func (p *postRepository) Store(ctx context.Context, post *domain.Post) error {
+	err := p.b.Do(ctx, func() error {
+		return p.next.Store(ctx, post)
+	})
+	if err == nil {
+		p.forget(post.ID)
+	}
+
+	return err
+}
+
+func (p *postRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	key := "post:fetch:" + strconv.FormatInt(num, 10) + ":" + cursor
+	writes := p.written()
+	err = p.b.Do(ctx, func() (err error) {
+		res, nextCursor, err = p.next.Fetch(ctx, cursor, num)
+		return
+	})
+	if p.stale == nil {
+		return
+	}
+
+	// the usecase fills the authors in the returned slice, the cache keeps a copy of its own
+	switch {
+	case err == nil:
+		p.keep(writes, key, page{posts: append([]domain.Post(nil), res...), nextCursor: nextCursor, writes: writes})
+	case unavailable(err):
+		if kept, ok := p.stale.Get(key); ok && kept.(page).writes == p.written() {
+			kept := kept.(page)
+			return append([]domain.Post(nil), kept.posts...), kept.nextCursor, nil
+		}
+	}
+
+	return
+}
+
+func (p *postRepository) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	writes := p.written()
+	err = p.b.Do(ctx, func() (err error) {
+		res, err = p.next.GetByID(ctx, id)
+		return
+	})
+	if p.stale == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		p.keep(writes, postIDKey(id), res)
+	case unavailable(err):
+		if kept, ok := p.stale.Get(postIDKey(id)); ok {
+			return kept.(domain.Post), nil
+		}
+	}
+
+	return
+}
+
+func (p *postRepository) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	writes := p.written()
+	err = p.b.Do(ctx, func() (err error) {
+		res, err = p.next.GetByTitle(ctx, title)
+		return
+	})
+	if p.stale == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		p.keep(writes, postIDKey(res.ID), res)
+		p.keep(writes, postTitleKey(title), res.ID)
+	case unavailable(err):
+		id, ok := p.stale.Get(postTitleKey(title))
+		if !ok {
+			break
+		}
+		// the post was renamed or deleted since it was read by this title
+		if kept, ok := p.stale.Get(postIDKey(id.(int64))); ok && kept.(domain.Post).Title == title {
+			return kept.(domain.Post), nil
+		}
+	}
+
+	return
+}
+
+func (p *postRepository) written() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.writes
+}
+
+// keep stores the result of a read started when writes were counted, unless a write happened since
+func (p *postRepository) keep(writes uint64, key string, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.writes == writes {
+		p.stale.Set(key, value)
+	}
+}
+
+func (p *postRepository) Update(ctx context.Context, post *domain.Post) error {
+	err := p.b.Do(ctx, func() error {
+		return p.next.Update(ctx, post)
+	})
+	if err == nil {
+		p.forget(post.ID)
+	}
+
+	return err
+}
+
+func (p *postRepository) Delete(ctx context.Context, id int64) error {
+	err := p.b.Do(ctx, func() error {
+		return p.next.Delete(ctx, id)
+	})
+	if err == nil {
+		p.forget(id)
+	}
+
+	return err
+}
+
+// forget drops the post of id, with it the titles pointing at it, and the pages
+func (p *postRepository) forget(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.writes++
+	if p.stale != nil {
+		p.stale.Delete(postIDKey(id))
+	}
+}
+
+func unavailable(err error) bool {
+	return errors.Is(err, domain.ErrUnavailable)
+}
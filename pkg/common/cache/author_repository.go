@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type authorRepository struct {
+	next  domain.AuthorRepository
+	cache *Cache
+}
+
+// NewAuthorRepository will wrap a domain.AuthorRepository, caching the authors read by id.
+// Within a transaction the reads go to the repository, so that they see the writes of the transaction.
+func NewAuthorRepository(next domain.AuthorRepository, cache *Cache) domain.AuthorRepository {
+	return &authorRepository{
+		next:  next,
+		cache: cache,
+	}
+}
+
+func authorKey(id int64) string {
+	return "author:" + strconv.FormatInt(id, 10)
+}
+
+func (a *authorRepository) GetByID(ctx context.Context, id int64) (res domain.Author, err error) {
+	if repository.InTx(ctx) {
+		return a.next.GetByID(ctx, id)
+	}
+
+	err = a.cache.Get(ctx, authorKey(id), &res, func(ctx context.Context) (interface{}, error) {
+		return a.next.GetByID(ctx, id)
+	})
+	if err != nil {
+		return domain.Author{}, err
+	}
+
+	return
+}
+
+// Store needs no invalidation, the misses are not cached and a stored author has a new id
+func (a *authorRepository) Store(ctx context.Context, author *domain.Author) error {
+	return a.next.Store(ctx, author)
+}
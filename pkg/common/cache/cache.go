@@ -0,0 +1,177 @@
+// Package cache keeps recently read values, so that reading them again costs no query. The reads
+// of the usecases and repositories are cached by decorators wrapping the domain interfaces.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Backend stores the encoded values of a Cache. The memory backend keeps them in the process, a
+// backend shared by the instances of the service lets a write on one invalidate the others.
+type Backend interface {
+	// Get returns the value stored under key, ok is false when the key is missing or expired
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys, a missing key is no error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Observer is told about every lookup of a cache, metrics.Metrics counts them
+type Observer interface {
+	ObserveCache(cache string, hit bool)
+}
+
+// Cache reads values through a Backend. A miss loads the value once however many callers miss it
+// at the same time, and stores it for TTL.
+type Cache struct {
+	// Name tells the cache apart in the metrics and the logs
+	Name    string
+	Backend Backend
+	TTL     time.Duration
+	// Timeout bounds a load, which runs apart from the requests waiting for it
+	Timeout time.Duration
+	// Observer may be nil
+	Observer Observer
+	Logger   logrus.FieldLogger
+
+	group singleflight.Group
+
+	// mu orders the stores of the loads with Invalidate. generation is bumped by every Invalidate,
+	// a load started before it does not store its value.
+	mu         sync.Mutex
+	generation uint64
+}
+
+// NewCache will create a cache storing its values in backend for ttl, a load runs for up to timeout
+func NewCache(name string, backend Backend, ttl, timeout time.Duration, observer Observer, logger logrus.FieldLogger) *Cache {
+	return &Cache{
+		Name:     name,
+		Backend:  backend,
+		TTL:      ttl,
+		Timeout:  timeout,
+		Observer: observer,
+		Logger:   logger,
+	}
+}
+
+// Get decodes the value of key into dst. On a miss load reads the value, the concurrent misses of
+// key wait for the same load and share its result, error included. The load gets a context of its
+// own, bounded by Timeout and reading from the primary. It keeps the values of the caller starting
+// it, such as its trace span and request info, but not its cancellation: each caller only stops
+// waiting when its ctx is done. A failing backend only costs the load, its errors are logged.
+func (c *Cache) Get(ctx context.Context, key string, dst interface{}, load func(ctx context.Context) (interface{}, error)) error {
+	value, ok, err := c.Backend.Get(ctx, key)
+	if err != nil {
+		c.Logger.WithError(err).WithField("cache", c.Name).Warn("reading the cache")
+	}
+	if ok {
+		if err = json.Unmarshal(value, dst); err == nil {
+			c.observe(true)
+			return nil
+		}
+		c.Logger.WithError(err).WithField("cache", c.Name).Warn("decoding a cached value")
+	}
+	c.observe(false)
+
+	loaded := c.group.DoChan(key, func() (interface{}, error) {
+		return c.load(detached{ctx}, key, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-loaded:
+		if res.Err != nil {
+			return res.Err
+		}
+		return json.Unmarshal(res.Val.([]byte), dst)
+	}
+}
+
+// load runs the load shared by the callers missing key and stores its encoded value
+func (c *Cache) load(parent context.Context, key string, load func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(repository.WithPrimary(parent), c.Timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	loaded, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(loaded)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(ctx, key, encoded, generation)
+	return encoded, nil
+}
+
+// detached carries the values of its parent without its deadline and cancellation
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// store keeps the value of a load started at generation, unless an Invalidate happened since: it
+// may have removed a newer value than the one loaded
+func (c *Cache) store(ctx context.Context, key string, value []byte, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+
+	if err := c.Backend.Set(ctx, key, value, c.TTL); err != nil {
+		c.Logger.WithError(err).WithField("cache", c.Name).Warn("writing the cache")
+	}
+}
+
+// Invalidate removes the values of keys, the next reads load them again
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) {
+	c.mu.Lock()
+	c.generation++
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		c.group.Forget(key)
+	}
+
+	if err := c.Backend.Delete(ctx, keys...); err != nil {
+		c.Logger.WithError(err).WithField("cache", c.Name).Error("invalidating the cache")
+	}
+}
+
+func (c *Cache) observe(hit bool) {
+	if c.Observer != nil {
+		c.Observer.ObserveCache(c.Name, hit)
+	}
+}
@@ -0,0 +1,254 @@
+package cache_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/cache"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type countingObserver struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (o *countingObserver) ObserveCache(name string, hit bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if hit {
+		o.counts[name+".hit"]++
+	} else {
+		o.counts[name+".miss"]++
+	}
+}
+
+func newCache(ttl time.Duration) (*cache.Cache, *countingObserver) {
+	observer := &countingObserver{counts: map[string]int{}}
+	return cache.NewCache("test", cache.NewMemoryBackend(10), ttl, time.Second, observer, logrus.New()), observer
+}
+
+func TestLRUEvictsOldest(t *testing.T) {
+	lru := cache.NewLRU(2)
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	lru.Get("a")
+	lru.Set("c", 3)
+
+	_, ok := lru.Get("b")
+	assert.False(t, ok)
+	value, ok := lru.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, lru.Len())
+}
+
+func TestCacheGet(t *testing.T) {
+	c, observer := newCache(time.Minute)
+
+	loads := 0
+	load := func(context.Context) (interface{}, error) {
+		loads++
+		return domain.Author{ID: 1, Name: "Iman Tumorang"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		var res domain.Author
+		require.NoError(t, c.Get(context.TODO(), "author:1", &res, load))
+		assert.Equal(t, "Iman Tumorang", res.Name)
+	}
+
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, 2, observer.counts["test.hit"])
+	assert.Equal(t, 1, observer.counts["test.miss"])
+}
+
+func TestCacheExpires(t *testing.T) {
+	c, _ := newCache(10 * time.Millisecond)
+
+	loads := 0
+	load := func(context.Context) (interface{}, error) {
+		loads++
+		return loads, nil
+	}
+
+	var res int
+	require.NoError(t, c.Get(context.TODO(), "key", &res, load))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, c.Get(context.TODO(), "key", &res, load))
+
+	assert.Equal(t, 2, res)
+}
+
+func TestCacheCollapsesMisses(t *testing.T) {
+	c, _ := newCache(time.Minute)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	loads := 0
+	load := func(context.Context) (interface{}, error) {
+		mu.Lock()
+		loads++
+		mu.Unlock()
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var res string
+			assert.NoError(t, c.Get(context.TODO(), "key", &res, load))
+			assert.Equal(t, "value", res)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, loads)
+}
+
+func TestCacheLoadOutlivesCallers(t *testing.T) {
+	c, _ := newCache(time.Minute)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var loadCtx context.Context
+	load := func(ctx context.Context) (interface{}, error) {
+		loadCtx = ctx
+		close(started)
+		<-release
+		return "value", ctx.Err()
+	}
+
+	// the caller starting the load goes away, the load keeps running for the other callers
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.TODO(), "request")
+	defer span.End()
+	ctx, cancel := context.WithCancel(domain.NewContextWithRequestInfo(ctx, domain.RequestInfo{Actor: "alice", IP: "10.0.0.1"}))
+	errFirst := make(chan error)
+	go func() {
+		var res string
+		errFirst <- c.Get(ctx, "key", &res, load)
+	}()
+	<-started
+
+	var res string
+	errSecond := make(chan error)
+	go func() {
+		errSecond <- c.Get(context.TODO(), "key", &res, load)
+	}()
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-errFirst)
+	close(release)
+	require.NoError(t, <-errSecond)
+	assert.Equal(t, "value", res)
+
+	// the load keeps the request of the caller starting it, its reads go to the primary
+	assert.Equal(t, domain.RequestInfo{Actor: "alice", IP: "10.0.0.1"}, domain.RequestInfoFromContext(loadCtx))
+	assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(loadCtx))
+	primary, replica := new(sql.DB), new(sql.DB)
+	router := repository.NewRouter(primary, []*sql.DB{replica}, time.Second, logrus.New())
+	assert.Equal(t, primary, router.Reader(loadCtx))
+}
+
+func TestCacheInvalidateDuringLoad(t *testing.T) {
+	c, _ := newCache(time.Minute)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	loads := 0
+	load := func(context.Context) (interface{}, error) {
+		loads++
+		if loads == 1 {
+			close(started)
+			<-release
+		}
+		return loads, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var res int
+		assert.NoError(t, c.Get(context.TODO(), "key", &res, load))
+	}()
+
+	// a write lands while the first load runs, the value it read is not kept
+	<-started
+	c.Invalidate(context.TODO(), "key")
+	close(release)
+	<-done
+
+	var res int
+	require.NoError(t, c.Get(context.TODO(), "key", &res, load))
+	assert.Equal(t, 2, res)
+}
+
+func TestPostUsecaseInvalidates(t *testing.T) {
+	post := domain.Post{ID: 1, Title: "Title", Author: domain.Author{ID: 1, Name: "Iman Tumorang"}}
+	mockUsecase := new(mocks.PostUsecase)
+	mockUsecase.On("GetByID", mock.Anything, int64(1)).Return(post, nil)
+	mockUsecase.On("Update", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(nil).Once()
+	mockUsecase.On("Delete", mock.Anything, int64(1)).Return(domain.ErrNotFound).Once()
+
+	c, observer := newCache(time.Minute)
+	u := cache.NewPostUsecase(mockUsecase, c)
+
+	res, err := u.GetByID(context.TODO(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, post, res)
+	_, err = u.GetByID(context.TODO(), 1)
+	require.NoError(t, err)
+	mockUsecase.AssertNumberOfCalls(t, "GetByID", 1)
+
+	t.Run("update", func(t *testing.T) {
+		require.NoError(t, u.Update(context.TODO(), &post))
+		_, err := u.GetByID(context.TODO(), 1)
+
+		require.NoError(t, err)
+		mockUsecase.AssertNumberOfCalls(t, "GetByID", 2)
+	})
+
+	t.Run("failed delete", func(t *testing.T) {
+		assert.Equal(t, domain.ErrNotFound, u.Delete(context.TODO(), 1))
+		_, err := u.GetByID(context.TODO(), 1)
+
+		require.NoError(t, err)
+		mockUsecase.AssertNumberOfCalls(t, "GetByID", 2)
+	})
+
+	assert.Equal(t, 2, observer.counts["test.hit"])
+	assert.Equal(t, 2, observer.counts["test.miss"])
+}
+
+func TestPostUsecaseDoesNotCacheErrors(t *testing.T) {
+	mockUsecase := new(mocks.PostUsecase)
+	mockUsecase.On("GetByID", mock.Anything, int64(1)).Return(domain.Post{}, domain.ErrNotFound)
+
+	c, _ := newCache(time.Minute)
+	u := cache.NewPostUsecase(mockUsecase, c)
+
+	for i := 0; i < 2; i++ {
+		res, err := u.GetByID(context.TODO(), 1)
+
+		assert.Equal(t, domain.ErrNotFound, err)
+		assert.Equal(t, domain.Post{}, res)
+	}
+	mockUsecase.AssertNumberOfCalls(t, "GetByID", 2)
+}
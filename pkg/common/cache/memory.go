@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type memoryBackend struct {
+	lru *LRU
+}
+
+type expiring struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryBackend will create a Backend keeping up to size values in the process, the least
+// recently used value is evicted first
+func NewMemoryBackend(size int) Backend {
+	return &memoryBackend{
+		lru: NewLRU(size),
+	}
+}
+
+func (m *memoryBackend) Get(ctx context.Context, key string) (value []byte, ok bool, err error) {
+	kept, ok := m.lru.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := kept.(expiring)
+	if !time.Now().Before(e.expiresAt) {
+		m.lru.Delete(key)
+		return nil, false, nil
+	}
+
+	return e.value, true, nil
+}
+
+func (m *memoryBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.lru.Set(key, expiring{value: value, expiresAt: time.Now().Add(ttl)})
+	return nil
+}
+
+func (m *memoryBackend) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.lru.Delete(key)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type postUsecase struct {
+	next  domain.PostUsecase
+	cache *Cache
+}
+
+// NewPostUsecase will wrap a domain.PostUsecase, caching the posts read by id with their author.
+// Update and Delete invalidate the post, a post read by title or fetched in a page is not cached.
+// An author changed under a cached post shows once the post expires.
+func NewPostUsecase(next domain.PostUsecase, cache *Cache) domain.PostUsecase {
+	return &postUsecase{
+		next:  next,
+		cache: cache,
+	}
+}
+
+func postKey(id int64) string {
+	return "post:" + strconv.FormatInt(id, 10)
+}
+
+func (p *postUsecase) Store(ctx context.Context, post *domain.Post) error {
+	return p.next.Store(ctx, post)
+}
+
+func (p *postUsecase) Fetch(ctx context.Context, cursor string, num int64) ([]domain.Post, string, error) {
+	return p.next.Fetch(ctx, cursor, num)
+}
+
+func (p *postUsecase) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	err = p.cache.Get(ctx, postKey(id), &res, func(ctx context.Context) (interface{}, error) {
+		return p.next.GetByID(ctx, id)
+	})
+	if err != nil {
+		return domain.Post{}, err
+	}
+
+	return
+}
+
+func (p *postUsecase) GetByTitle(ctx context.Context, title string) (domain.Post, error) {
+	return p.next.GetByTitle(ctx, title)
+}
+
+func (p *postUsecase) Update(ctx context.Context, post *domain.Post) error {
+	err := p.next.Update(ctx, post)
+	if err == nil {
+		p.cache.Invalidate(ctx, postKey(post.ID))
+	}
+
+	return err
+}
+
+func (p *postUsecase) Delete(ctx context.Context, id int64) error {
+	err := p.next.Delete(ctx, id)
+	if err == nil {
+		p.cache.Invalidate(ctx, postKey(id))
+	}
+
+	return err
+}
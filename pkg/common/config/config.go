@@ -0,0 +1,602 @@
+// Package config loads the typed service configuration from, in increasing precedence,
+// defaults, a config file, APP_ prefixed environment variables and command line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix prefixes the environment variables overriding config keys, database.pass is read from APP_DATABASE_PASS
+const EnvPrefix = "APP"
+
+// EnvConfigFile names the environment variable choosing the config file when no flag does
+const EnvConfigFile = EnvPrefix + "_CONFIG"
+
+// DefaultFile is the config file read when none is chosen, it may be missing
+const DefaultFile = "config.json"
+
+// fileSuffix marks an environment variable holding the path of a file with the value, e.g. APP_DATABASE_PASS_FILE
+const fileSuffix = "_FILE"
+
+// Config represent the whole service configuration
+type Config struct {
+	Debug       bool              `mapstructure:"debug"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Context     ContextConfig     `mapstructure:"context"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	RateLimit   RateLimitConfig   `mapstructure:"ratelimit"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Cache       CacheConfig       `mapstructure:"cache"`
+	Admin       AdminConfig       `mapstructure:"admin"`
+	Health      HealthConfig      `mapstructure:"health"`
+	Metrics     MetricsConfig     `mapstructure:"metrics"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Log         LogConfig         `mapstructure:"log"`
+}
+
+// ServerConfig represent the http server configuration
+type ServerConfig struct {
+	Address string `mapstructure:"address"`
+	// ShutdownTimeout is how many seconds in-flight requests and background workers get to finish
+	ShutdownTimeout int `mapstructure:"shutdown_timeout"`
+}
+
+// ContextConfig represent the request and usecase context configuration
+type ContextConfig struct {
+	// Timeout of a request, and of a usecase call made outside of a request, in seconds
+	Timeout int `mapstructure:"timeout"`
+	// Routes maps a path prefix to the timeout of its requests in seconds, the longest prefix matching
+	// whole path segments wins
+	Routes map[string]int `mapstructure:"routes"`
+}
+
+// DatabaseConfig represent the database connection configuration
+type DatabaseConfig struct {
+	// Kind is one of mysql, postgres, sqlite or memory, which keeps everything in the process
+	Kind string `mapstructure:"kind"`
+	Host string `mapstructure:"host"`
+	Port string `mapstructure:"port"`
+	User string `mapstructure:"user"`
+	Pass string `mapstructure:"pass"`
+	// Name of the database, the path of the database file for sqlite
+	Name    string `mapstructure:"name"`
+	Migrate bool   `mapstructure:"migrate"`
+	// Timezone of the session, an IANA name such as Asia/Jakarta, empty keeps the driver default
+	Timezone string `mapstructure:"timezone"`
+	// ConnectTimeout in seconds, 0 waits forever
+	ConnectTimeout int `mapstructure:"connect_timeout"`
+	// ApplicationName is reported to postgres, mysql has no equivalent
+	ApplicationName string         `mapstructure:"application_name"`
+	TLS             TLSConfig      `mapstructure:"tls"`
+	Pool            PoolConfig     `mapstructure:"pool"`
+	Replicas        ReplicasConfig `mapstructure:"replicas"`
+	Retry           RetryConfig    `mapstructure:"retry"`
+	Breaker         BreakerConfig  `mapstructure:"breaker"`
+}
+
+// BreakerConfig represent the circuit breaker failing the repository calls fast while the database is failing
+type BreakerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window the calls are counted over, in milliseconds
+	Window int `mapstructure:"window"`
+	// MinCalls of a window before its error rate can open the breaker
+	MinCalls int `mapstructure:"min_calls"`
+	// ErrorRate is the fraction of failed calls of a window opening the breaker
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// OpenTimeout is how long the open breaker fails the calls before probing the database, in milliseconds
+	OpenTimeout int `mapstructure:"open_timeout"`
+	// StaleEntries is how many read results are kept to be served while the breaker is open, 0 serves none
+	StaleEntries int `mapstructure:"stale_entries"`
+}
+
+// RetryConfig represent the retries of the repository reads failing with a transient error
+type RetryConfig struct {
+	// Attempts of a read, the first one included, 1 never retries
+	Attempts int `mapstructure:"attempts"`
+	// BaseDelay is the backoff after the first attempt, in milliseconds, it doubles after each attempt
+	BaseDelay int `mapstructure:"base_delay"`
+	// MaxDelay caps the backoff, in milliseconds
+	MaxDelay int `mapstructure:"max_delay"`
+}
+
+// ReplicasConfig represent the read replicas of the database. They are reached with the
+// user, password, name, TLS and pool settings of the primary.
+type ReplicasConfig struct {
+	// Hosts are the host:port addresses of the replicas, none sends every read to the primary
+	Hosts []string `mapstructure:"hosts"`
+	// CheckInterval between the health checks ejecting and admitting the replicas, in milliseconds
+	CheckInterval int `mapstructure:"check_interval"`
+	// ReadYourWrites is how long the reads of a client go to the primary after it wrote, in milliseconds
+	ReadYourWrites int `mapstructure:"read_your_writes"`
+}
+
+// TLS modes of the database connection, named after the postgres sslmode values
+const (
+	TLSModeDisable    = "disable"
+	TLSModeRequire    = "require"
+	TLSModeVerifyCA   = "verify-ca"
+	TLSModeVerifyFull = "verify-full"
+)
+
+// TLSConfig represent the TLS settings of the database connection
+type TLSConfig struct {
+	Mode string `mapstructure:"mode"`
+	// CA is the path of the PEM file of the certificate authorities verifying the server
+	CA string `mapstructure:"ca"`
+	// Cert and Key are the paths of the PEM client certificate and its private key
+	Cert string `mapstructure:"cert"`
+	Key  string `mapstructure:"key"`
+}
+
+// PoolConfig represent the limits of the database connection pool, 0 means unlimited
+type PoolConfig struct {
+	MaxOpenConns int `mapstructure:"max_open_conns"`
+	MaxIdleConns int `mapstructure:"max_idle_conns"`
+	// ConnMaxLifetime in seconds
+	ConnMaxLifetime int `mapstructure:"conn_max_lifetime"`
+	// ConnMaxIdleTime in seconds
+	ConnMaxIdleTime int `mapstructure:"conn_max_idle_time"`
+}
+
+// HealthConfig represent the readiness checks configuration
+type HealthConfig struct {
+	// Timeout of each readiness check, in milliseconds
+	Timeout int `mapstructure:"timeout"`
+}
+
+// MetricsConfig represent the Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Path the metrics are served from
+	Path string `mapstructure:"path"`
+}
+
+// LogConfig represent the logging configuration
+type LogConfig struct {
+	// Level is one of trace, debug, info, warn, error, fatal or panic, debug forces debug
+	Level string `mapstructure:"level"`
+}
+
+// Exporters of the tracing spans
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// TracingConfig represent the OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Exporter is one of none, stdout or otlp
+	Exporter string `mapstructure:"exporter"`
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure sends the spans to the collector over plain http
+	Insecure    bool   `mapstructure:"insecure"`
+	ServiceName string `mapstructure:"service_name"`
+	// SampleRatio is the fraction of the new traces recorded, the incoming sampled traces are always recorded
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// AdminConfig represent the admin endpoints configuration
+type AdminConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Token required as a bearer token, it must be set when the endpoints are enabled
+	Token string `mapstructure:"token"`
+}
+
+// RateLimitConfig represent the rate limiting configuration
+type RateLimitConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Store   string `mapstructure:"store"`
+	// Groups maps a route prefix to its limit
+	Groups map[string]RateLimitGroup `mapstructure:"groups"`
+}
+
+// RateLimitGroup represent the limit configured for a route group
+type RateLimitGroup struct {
+	Limit int64 `mapstructure:"limit"`
+	// Window in seconds
+	Window int    `mapstructure:"window"`
+	Key    string `mapstructure:"key"`
+}
+
+// IdempotencyConfig represent the Idempotency-Key configuration
+type IdempotencyConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Store   string `mapstructure:"store"`
+	// TTL of a stored response, in seconds
+	TTL int `mapstructure:"ttl"`
+	// Wait for an in flight request with the same key, in milliseconds
+	Wait int `mapstructure:"wait"`
+}
+
+// CacheConfig represent the cache of the posts and authors read by id
+type CacheConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Backend string `mapstructure:"backend"`
+	// Size is how many values the memory backend keeps
+	Size int `mapstructure:"size"`
+	// TTL of a cached value, in seconds
+	TTL int `mapstructure:"ttl"`
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("debug", false)
+	v.SetDefault("server.address", ":8080")
+	v.SetDefault("server.shutdown_timeout", 10)
+	v.SetDefault("context.timeout", 2)
+	v.SetDefault("database.kind", "postgres")
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", "5432")
+	v.SetDefault("database.user", "")
+	v.SetDefault("database.pass", "")
+	v.SetDefault("database.name", "post")
+	v.SetDefault("database.migrate", false)
+	v.SetDefault("database.timezone", "")
+	v.SetDefault("database.connect_timeout", 5)
+	v.SetDefault("database.application_name", "poc-gofiber-clean-arch")
+	v.SetDefault("database.tls.mode", TLSModeDisable)
+	v.SetDefault("database.tls.ca", "")
+	v.SetDefault("database.tls.cert", "")
+	v.SetDefault("database.tls.key", "")
+	v.SetDefault("database.pool.max_open_conns", 0)
+	v.SetDefault("database.pool.max_idle_conns", 2)
+	v.SetDefault("database.pool.conn_max_lifetime", 0)
+	v.SetDefault("database.pool.conn_max_idle_time", 0)
+	v.SetDefault("database.replicas.hosts", []string{})
+	v.SetDefault("database.replicas.check_interval", 5000)
+	v.SetDefault("database.replicas.read_your_writes", 2000)
+	v.SetDefault("database.retry.attempts", 3)
+	v.SetDefault("database.retry.base_delay", 20)
+	v.SetDefault("database.retry.max_delay", 500)
+	v.SetDefault("database.breaker.enabled", false)
+	v.SetDefault("database.breaker.window", 10000)
+	v.SetDefault("database.breaker.min_calls", 20)
+	v.SetDefault("database.breaker.error_rate", 0.5)
+	v.SetDefault("database.breaker.open_timeout", 5000)
+	v.SetDefault("database.breaker.stale_entries", 0)
+	v.SetDefault("ratelimit.enabled", false)
+	v.SetDefault("ratelimit.store", "memory")
+	v.SetDefault("idempotency.enabled", false)
+	v.SetDefault("idempotency.store", "memory")
+	v.SetDefault("idempotency.ttl", 86400)
+	v.SetDefault("idempotency.wait", 0)
+	v.SetDefault("cache.enabled", false)
+	v.SetDefault("cache.backend", "memory")
+	v.SetDefault("cache.size", 10000)
+	v.SetDefault("cache.ttl", 60)
+	v.SetDefault("admin.enabled", false)
+	v.SetDefault("admin.token", "")
+	v.SetDefault("health.timeout", 1000)
+	v.SetDefault("metrics.enabled", false)
+	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("tracing.exporter", TracingExporterNone)
+	v.SetDefault("tracing.endpoint", "localhost:4318")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.service_name", "poc-gofiber-clean-arch")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+}
+
+// Load reads the configuration and validates it.
+// file is the config file chosen by flag, when empty APP_CONFIG and then DefaultFile are tried.
+// flags maps a config key, e.g. server.address, to the command line flag overriding it.
+func Load(file string, flags map[string]*pflag.Flag) (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	err := readFile(v, file)
+	if err != nil {
+		return nil, err
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for key, flag := range flags {
+		if err = v.BindPFlag(key, flag); err != nil {
+			return nil, err
+		}
+	}
+
+	err = readSecretFiles(v, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	err = v.Unmarshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func readFile(v *viper.Viper, file string) error {
+	explicit := true
+	if file == "" {
+		file = os.Getenv(EnvConfigFile)
+	}
+	if file == "" {
+		file, explicit = DefaultFile, false
+	}
+
+	v.SetConfigFile(file)
+	err := v.ReadInConfig()
+	if err == nil {
+		return nil
+	}
+
+	// only a chosen file has to exist
+	if !explicit && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return fmt.Errorf("config: reading %s: %w", file, err)
+}
+
+// readSecretFiles reads the value of every key having an APP_<KEY>_FILE variable from that file,
+// so that secrets such as database.pass never have to be written in the config file
+func readSecretFiles(v *viper.Viper, flags map[string]*pflag.Flag) error {
+	for _, key := range v.AllKeys() {
+		env := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		path, ok := os.LookupEnv(env + fileSuffix)
+		if !ok {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(env); ok {
+			return fmt.Errorf("config: both %s and %s are set", env, env+fileSuffix)
+		}
+
+		// flags still take precedence over the environment
+		if flag, ok := flags[key]; ok && flag.Changed {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("config: reading %s: %w", env+fileSuffix, err)
+		}
+
+		v.Set(key, strings.TrimRight(string(content), "\r\n"))
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var problems []string
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Address == "" {
+		invalid("server.address is required")
+	}
+
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout must be a positive number of seconds, got %d", c.Server.ShutdownTimeout)
+	}
+
+	if c.Context.Timeout <= 0 {
+		invalid("context.timeout must be a positive number of seconds, got %d", c.Context.Timeout)
+	}
+
+	routes := make([]string, 0, len(c.Context.Routes))
+	for prefix := range c.Context.Routes {
+		routes = append(routes, prefix)
+	}
+	sort.Strings(routes)
+
+	for _, prefix := range routes {
+		if !strings.HasPrefix(prefix, "/") {
+			invalid("context.routes key must be a path starting with /, got %q", prefix)
+		}
+		if c.Context.Routes[prefix] <= 0 {
+			invalid("context.routes.%s must be a positive number of seconds, got %d", prefix, c.Context.Routes[prefix])
+		}
+	}
+
+	switch c.Database.Kind {
+	case "mysql", "postgres", "sqlite", "memory":
+	default:
+		invalid("database.kind must be one of mysql, postgres, sqlite, memory, got %q", c.Database.Kind)
+	}
+
+	// sqlite opens the file named by database.name and memory opens nothing, there is no server to reach
+	if c.Database.Host == "" && c.Database.Kind != "sqlite" && c.Database.Kind != "memory" {
+		invalid("database.host is required")
+	}
+
+	if c.Database.Name == "" && c.Database.Kind != "memory" {
+		invalid("database.name is required")
+	}
+
+	if c.Database.Timezone != "" {
+		if _, err := time.LoadLocation(c.Database.Timezone); err != nil {
+			invalid("database.timezone %q is not a known timezone", c.Database.Timezone)
+		}
+	}
+
+	if c.Database.ConnectTimeout < 0 {
+		invalid("database.connect_timeout must not be negative, got %d", c.Database.ConnectTimeout)
+	}
+
+	switch c.Database.TLS.Mode {
+	case TLSModeDisable, TLSModeRequire, TLSModeVerifyCA, TLSModeVerifyFull:
+	default:
+		invalid("database.tls.mode must be one of disable, require, verify-ca, verify-full, got %q", c.Database.TLS.Mode)
+	}
+
+	if (c.Database.TLS.Cert == "") != (c.Database.TLS.Key == "") {
+		invalid("database.tls.cert and database.tls.key must be set together")
+	}
+
+	pool := c.Database.Pool
+	if pool.MaxOpenConns < 0 || pool.MaxIdleConns < 0 || pool.ConnMaxLifetime < 0 || pool.ConnMaxIdleTime < 0 {
+		invalid("database.pool limits must not be negative")
+	}
+
+	if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		invalid("database.pool.max_idle_conns (%d) must not exceed database.pool.max_open_conns (%d)", pool.MaxIdleConns, pool.MaxOpenConns)
+	}
+
+	replicas := c.Database.Replicas
+	if len(replicas.Hosts) > 0 {
+		if c.Database.Kind != "mysql" && c.Database.Kind != "postgres" {
+			invalid("database.replicas are not supported by database.kind %s", c.Database.Kind)
+		}
+		for _, host := range replicas.Hosts {
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				invalid("database.replicas.hosts entry %q must be a host:port address", host)
+			}
+		}
+		if replicas.CheckInterval <= 0 {
+			invalid("database.replicas.check_interval must be a positive number of milliseconds, got %d", replicas.CheckInterval)
+		}
+		if replicas.ReadYourWrites < 0 {
+			invalid("database.replicas.read_your_writes must not be negative, got %d", replicas.ReadYourWrites)
+		}
+	}
+
+	retry := c.Database.Retry
+	if retry.Attempts < 1 {
+		invalid("database.retry.attempts must be at least 1, got %d", retry.Attempts)
+	}
+	if retry.BaseDelay <= 0 || retry.MaxDelay < retry.BaseDelay {
+		invalid("database.retry.base_delay (%d) must be positive and not exceed database.retry.max_delay (%d)", retry.BaseDelay, retry.MaxDelay)
+	}
+
+	if breaker := c.Database.Breaker; breaker.Enabled {
+		if breaker.Window <= 0 || breaker.OpenTimeout <= 0 {
+			invalid("database.breaker.window and database.breaker.open_timeout must be positive numbers of milliseconds")
+		}
+		if breaker.MinCalls < 1 {
+			invalid("database.breaker.min_calls must be at least 1, got %d", breaker.MinCalls)
+		}
+		if breaker.ErrorRate <= 0 || breaker.ErrorRate > 1 {
+			invalid("database.breaker.error_rate must be greater than 0 and at most 1, got %g", breaker.ErrorRate)
+		}
+		if breaker.StaleEntries < 0 {
+			invalid("database.breaker.stale_entries must not be negative, got %d", breaker.StaleEntries)
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		validateStore(invalid, "ratelimit.store", c.RateLimit.Store, c.Database.Kind)
+
+		prefixes := make([]string, 0, len(c.RateLimit.Groups))
+		for prefix := range c.RateLimit.Groups {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+
+		for _, prefix := range prefixes {
+			group := c.RateLimit.Groups[prefix]
+			if group.Limit <= 0 {
+				invalid("ratelimit.groups.%s.limit must be positive, got %d", prefix, group.Limit)
+			}
+			if group.Window <= 0 {
+				invalid("ratelimit.groups.%s.window must be a positive number of seconds, got %d", prefix, group.Window)
+			}
+			switch group.Key {
+			case "ip", "user", "api_key":
+			default:
+				invalid("ratelimit.groups.%s.key must be one of ip, user, api_key, got %q", prefix, group.Key)
+			}
+		}
+	}
+
+	if c.Idempotency.Enabled {
+		validateStore(invalid, "idempotency.store", c.Idempotency.Store, c.Database.Kind)
+
+		if c.Idempotency.TTL <= 0 {
+			invalid("idempotency.ttl must be a positive number of seconds, got %d", c.Idempotency.TTL)
+		}
+		if c.Idempotency.Wait < 0 {
+			invalid("idempotency.wait must not be negative, got %d", c.Idempotency.Wait)
+		}
+	}
+
+	if c.Cache.Enabled {
+		if c.Cache.Backend != "memory" {
+			invalid("cache.backend must be memory, got %q", c.Cache.Backend)
+		}
+		if c.Cache.Size <= 0 {
+			invalid("cache.size must be positive, got %d", c.Cache.Size)
+		}
+		if c.Cache.TTL <= 0 {
+			invalid("cache.ttl must be a positive number of seconds, got %d", c.Cache.TTL)
+		}
+	}
+
+	if c.Health.Timeout <= 0 {
+		invalid("health.timeout must be a positive number of milliseconds, got %d", c.Health.Timeout)
+	}
+
+	// the pool statistics are never served without authentication
+	if c.Admin.Enabled && c.Admin.Token == "" {
+		invalid("admin.token is required when admin.enabled is true")
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		invalid("metrics.path must start with /, got %q", c.Metrics.Path)
+	}
+
+	switch c.Log.Level {
+	case "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+	default:
+		invalid("log.level must be one of trace, debug, info, warn, error, fatal, panic, got %q", c.Log.Level)
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if c.Tracing.Endpoint == "" {
+			invalid("tracing.endpoint is required by the otlp exporter")
+		}
+	default:
+		invalid("tracing.exporter must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func validateStore(invalid func(format string, args ...interface{}), key, store, dbKind string) {
+	switch store {
+	case "memory":
+	case "sql":
+		if dbKind == "memory" {
+			invalid("%s sql is not supported by database.kind %s, use memory", key, dbKind)
+		}
+	default:
+		invalid("%s must be one of memory, sql, got %q", key, store)
+	}
+}
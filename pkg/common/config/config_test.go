@@ -0,0 +1,299 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fileContent = `{
+  "server": { "address": ":9090" },
+  "database": { "kind": "mysql", "host": "db", "pass": "from-file" },
+  "ratelimit": {
+    "enabled": true,
+    "groups": { "/posts": { "limit": 10, "window": 60, "key": "ip" } }
+  }
+}`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func setenv(t *testing.T, key, value string) {
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		os.Unsetenv(key)
+	})
+}
+
+func TestLoadDefaults(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	cfg, err := config.Load("", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, ":8080", cfg.Server.Address)
+	assert.Equal(t, 2, cfg.Context.Timeout)
+	assert.Equal(t, "postgres", cfg.Database.Kind)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", fileContent)
+
+	cfg, err := config.Load(path, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.Address)
+	assert.Equal(t, "mysql", cfg.Database.Kind)
+	assert.Equal(t, "db", cfg.Database.Host)
+	// not in the file, so the default stays
+	assert.Equal(t, "post", cfg.Database.Name)
+	assert.Equal(t, config.RateLimitGroup{Limit: 10, Window: 60, Key: "ip"}, cfg.RateLimit.Groups["/posts"])
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", fileContent)
+	setenv(t, config.EnvConfigFile, path)
+
+	cfg, err := config.Load("", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.Address)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.json"), nil)
+
+	assert.Error(t, err)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", fileContent)
+	setenv(t, "APP_DATABASE_HOST", "env-host")
+	setenv(t, "APP_SERVER_ADDRESS", ":7070")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("address", "", "")
+	require.NoError(t, flags.Parse([]string{"--address", ":6060"}))
+
+	cfg, err := config.Load(path, map[string]*pflag.Flag{
+		"server.address": flags.Lookup("address"),
+	})
+
+	require.NoError(t, err)
+	// env wins over the file
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	// flags win over env
+	assert.Equal(t, ":6060", cfg.Server.Address)
+}
+
+func TestLoadUnchangedFlag(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", fileContent)
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("address", ":6060", "")
+
+	cfg, err := config.Load(path, map[string]*pflag.Flag{
+		"server.address": flags.Lookup("address"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.Address)
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.json", fileContent)
+	secret := writeFile(t, dir, "db_pass", "s3cret\n")
+	setenv(t, "APP_DATABASE_PASS_FILE", secret)
+
+	cfg, err := config.Load(path, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Database.Pass)
+}
+
+func TestLoadSecretFileConflict(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.json", fileContent)
+	secret := writeFile(t, dir, "db_pass", "s3cret")
+	setenv(t, "APP_DATABASE_PASS_FILE", secret)
+	setenv(t, "APP_DATABASE_PASS", "plain")
+
+	_, err := config.Load(path, nil)
+
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "context": { "timeout": 0, "routes": { "/posts": -1 } },
+  "database": { "kind": "oracle" },
+  "ratelimit": {
+    "enabled": true,
+    "store": "redis",
+    "groups": { "/posts": { "limit": 0, "window": 60, "key": "cookie" } }
+  }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "context.timeout")
+	assert.Contains(t, err.Error(), "context.routes./posts")
+	assert.Contains(t, err.Error(), "database.kind")
+	assert.Contains(t, err.Error(), "ratelimit.store")
+	assert.Contains(t, err.Error(), "ratelimit.groups./posts.limit")
+	assert.Contains(t, err.Error(), "ratelimit.groups./posts.key")
+}
+
+func TestValidateDatabase(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": {
+    "timezone": "Mars/Olympus_Mons",
+    "tls": { "mode": "prefer", "cert": "/certs/client.pem" },
+    "pool": { "max_open_conns": 2, "max_idle_conns": 5 }
+  }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.timezone")
+	assert.Contains(t, err.Error(), "database.tls.mode")
+	assert.Contains(t, err.Error(), "database.tls.cert and database.tls.key")
+	assert.Contains(t, err.Error(), "database.pool.max_idle_conns")
+}
+
+func TestValidateMemory(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": { "kind": "memory", "host": "", "name": "" },
+  "idempotency": { "enabled": true, "store": "sql" }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "database.host")
+	assert.NotContains(t, err.Error(), "database.name")
+	assert.Contains(t, err.Error(), "idempotency.store sql is not supported by database.kind memory")
+}
+
+func TestValidateSqliteStores(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": { "kind": "sqlite", "name": "post.db" },
+  "ratelimit": { "enabled": true, "store": "sql" },
+  "idempotency": { "enabled": true, "store": "sql" }
+}`)
+
+	cfg, err := config.Load(path, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "sql", cfg.RateLimit.Store)
+	assert.Equal(t, "sql", cfg.Idempotency.Store)
+}
+
+func TestValidateAdminToken(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": { "kind": "memory" },
+  "admin": { "enabled": true, "token": "" }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "admin.token is required when admin.enabled is true")
+}
+
+func TestLoadReplicasFromEnv(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", fileContent)
+	setenv(t, "APP_DATABASE_REPLICAS_HOSTS", "replica-1:3306,replica-2:3306")
+
+	cfg, err := config.Load(path, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"replica-1:3306", "replica-2:3306"}, cfg.Database.Replicas.Hosts)
+	assert.Equal(t, 5000, cfg.Database.Replicas.CheckInterval)
+}
+
+func TestValidateReplicas(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": {
+    "kind": "sqlite",
+    "replicas": { "hosts": ["replica-1"], "check_interval": 0, "read_your_writes": -1 }
+  }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.replicas are not supported by database.kind sqlite")
+	assert.Contains(t, err.Error(), `database.replicas.hosts entry "replica-1"`)
+	assert.Contains(t, err.Error(), "database.replicas.check_interval")
+	assert.Contains(t, err.Error(), "database.replicas.read_your_writes")
+}
+
+func TestValidateRetry(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": { "retry": { "attempts": 0, "base_delay": 100, "max_delay": 50 } }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.retry.attempts must be at least 1, got 0")
+	assert.Contains(t, err.Error(), "database.retry.base_delay (100)")
+}
+
+func TestValidateCache(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "cache": { "enabled": true, "backend": "redis", "size": 0, "ttl": -1 }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `cache.backend must be memory, got "redis"`)
+	assert.Contains(t, err.Error(), "cache.size must be positive, got 0")
+	assert.Contains(t, err.Error(), "cache.ttl must be a positive number of seconds, got -1")
+}
+
+func TestValidateBreaker(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "database": { "breaker": { "enabled": true, "window": 0, "min_calls": 0, "error_rate": 1.5, "stale_entries": -1 } }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.breaker.window")
+	assert.Contains(t, err.Error(), "database.breaker.min_calls")
+	assert.Contains(t, err.Error(), "database.breaker.error_rate must be greater than 0 and at most 1, got 1.5")
+	assert.Contains(t, err.Error(), "database.breaker.stale_entries")
+}
+
+func TestValidateObservability(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+  "tracing": { "exporter": "jaeger", "sample_ratio": 2 },
+  "log": { "level": "verbose" }
+}`)
+
+	_, err := config.Load(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tracing.exporter")
+	assert.Contains(t, err.Error(), "tracing.sample_ratio")
+	assert.Contains(t, err.Error(), "log.level")
+}
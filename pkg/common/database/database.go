@@ -0,0 +1,267 @@
+// Package database opens the configured sql database with its DSN options and pool limits.
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+)
+
+// ErrUnknownKind is returned for a database.kind no driver is wired for
+var ErrUnknownKind = errors.New("unknown database kind")
+
+// Open connects to the configured database, applies the pool limits and pings it
+func Open(cfg config.DatabaseConfig) (*sql.DB, error) {
+	driver, dsn, err := DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ApplyPool(db, cfg.Pool)
+	if cfg.Kind == "sqlite" {
+		// sqlite takes one writer at a time, a single connection queues the writes instead of failing them as busy
+		db.SetMaxOpenConns(1)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to %s at %s: %w", cfg.Kind, address(cfg), err)
+	}
+
+	return db, nil
+}
+
+// OpenReplicas opens the read replicas of cfg, reached with the settings of the primary. They are
+// not pinged, a replica down at startup takes no reads until a health check of the router passes.
+func OpenReplicas(cfg config.DatabaseConfig) (replicas []*sql.DB, err error) {
+	for _, address := range cfg.Replicas.Hosts {
+		replica := cfg
+		replica.Host, replica.Port, err = net.SplitHostPort(address)
+		if err != nil {
+			break
+		}
+
+		var driver, dsn string
+		driver, dsn, err = DSN(replica)
+		if err != nil {
+			break
+		}
+
+		var db *sql.DB
+		db, err = sql.Open(driver, dsn)
+		if err != nil {
+			break
+		}
+
+		ApplyPool(db, cfg.Pool)
+		replicas = append(replicas, db)
+	}
+
+	if err != nil {
+		for _, db := range replicas {
+			db.Close()
+		}
+		return nil, fmt.Errorf("opening the database replicas: %w", err)
+	}
+
+	return replicas, nil
+}
+
+// address names the database reached by cfg in the errors
+func address(cfg config.DatabaseConfig) string {
+	if cfg.Kind == "sqlite" {
+		return cfg.Name
+	}
+
+	return net.JoinHostPort(cfg.Host, cfg.Port)
+}
+
+// ApplyPool sets the connection pool limits of db
+func ApplyPool(db *sql.DB, cfg config.PoolConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+	db.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+}
+
+// DSN returns the driver name and data source name of the configured database
+func DSN(cfg config.DatabaseConfig) (driver, dsn string, err error) {
+	switch cfg.Kind {
+	case "postgres":
+		dsn, err = postgresDSN(cfg)
+		return "postgres", dsn, err
+	case "mysql":
+		dsn, err = mysqlDSN(cfg)
+		return "mysql", dsn, err
+	case "sqlite":
+		// the session options only apply to servers, sqlite just opens the file
+		return "sqlite", cfg.Name, nil
+	default:
+		return "", "", fmt.Errorf("%w %q, expected one of mysql, postgres, sqlite", ErrUnknownKind, cfg.Kind)
+	}
+}
+
+func postgresDSN(cfg config.DatabaseConfig) (string, error) {
+	val := url.Values{}
+
+	mode := cfg.TLS.Mode
+	if mode == "" {
+		mode = config.TLSModeDisable
+	}
+	val.Add("sslmode", mode)
+	if cfg.TLS.CA != "" {
+		val.Add("sslrootcert", cfg.TLS.CA)
+	}
+	if cfg.TLS.Cert != "" {
+		val.Add("sslcert", cfg.TLS.Cert)
+		val.Add("sslkey", cfg.TLS.Key)
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		val.Add("connect_timeout", strconv.Itoa(cfg.ConnectTimeout))
+	}
+	if cfg.ApplicationName != "" {
+		val.Add("application_name", cfg.ApplicationName)
+	}
+	// unknown parameters are sent by lib/pq as session settings
+	if cfg.Timezone != "" {
+		val.Add("timezone", cfg.Timezone)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Pass),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     "/" + cfg.Name,
+		RawQuery: val.Encode(),
+	}
+
+	return u.String(), nil
+}
+
+func mysqlDSN(cfg config.DatabaseConfig) (string, error) {
+	c := mysql.NewConfig()
+	c.User = cfg.User
+	c.Passwd = cfg.Pass
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	c.DBName = cfg.Name
+	c.ParseTime = true
+	// an UPDATE reports the rows it matched like postgres and sqlite do, not only those it changed,
+	// so that updating a row with its own values is not taken for a missing row
+	c.ClientFoundRows = true
+	c.Timeout = time.Duration(cfg.ConnectTimeout) * time.Second
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return "", err
+		}
+		c.Loc = loc
+	}
+
+	tlsName, err := mysqlTLS(cfg)
+	if err != nil {
+		return "", err
+	}
+	if tlsName != "" {
+		c.Params = map[string]string{"tls": tlsName}
+	}
+
+	return c.FormatDSN(), nil
+}
+
+// mysqlTLS registers the tls.Config of the connection with the driver and returns its name
+func mysqlTLS(cfg config.DatabaseConfig) (string, error) {
+	switch cfg.TLS.Mode {
+	case "", config.TLSModeDisable:
+		return "", nil
+	case config.TLSModeRequire:
+		if cfg.TLS.Cert == "" {
+			return "skip-verify", nil
+		}
+	}
+
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	if cfg.TLS.CA != "" {
+		pem, err := ioutil.ReadFile(cfg.TLS.CA)
+		if err != nil {
+			return "", fmt.Errorf("reading database.tls.ca: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("database.tls.ca %s holds no PEM certificate", cfg.TLS.CA)
+		}
+	}
+
+	if cfg.TLS.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.Cert, cfg.TLS.Key)
+		if err != nil {
+			return "", fmt.Errorf("loading database.tls.cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch cfg.TLS.Mode {
+	case config.TLSModeRequire:
+		tlsConfig.InsecureSkipVerify = true
+	case config.TLSModeVerifyCA:
+		// verify the chain but not the host name, like postgres does
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifyChain(tlsConfig.RootCAs)
+	}
+
+	name := "app-" + net.JoinHostPort(cfg.Host, cfg.Port)
+	err := mysql.RegisterTLSConfig(name, tlsConfig)
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+func verifyChain(roots *x509.CertPool) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("database server sent no certificate")
+		}
+
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+}
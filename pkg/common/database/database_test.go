@@ -0,0 +1,151 @@
+package database_test
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	driver, dsn, err := database.DSN(config.DatabaseConfig{
+		Kind:            "postgres",
+		Host:            "db",
+		Port:            "5432",
+		User:            "user",
+		Pass:            "p@ss/word",
+		Name:            "post",
+		Timezone:        "Asia/Jakarta",
+		ConnectTimeout:  5,
+		ApplicationName: "api",
+		TLS: config.TLSConfig{
+			Mode: config.TLSModeVerifyFull,
+			CA:   "/certs/ca.pem",
+			Cert: "/certs/client.pem",
+			Key:  "/certs/client.key",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", driver)
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+
+	pass, _ := u.User.Password()
+	assert.Equal(t, "p@ss/word", pass)
+	assert.Equal(t, "db:5432", u.Host)
+	assert.Equal(t, "/post", u.Path)
+
+	q := u.Query()
+	assert.Equal(t, "verify-full", q.Get("sslmode"))
+	assert.Equal(t, "/certs/ca.pem", q.Get("sslrootcert"))
+	assert.Equal(t, "/certs/client.pem", q.Get("sslcert"))
+	assert.Equal(t, "/certs/client.key", q.Get("sslkey"))
+	assert.Equal(t, "5", q.Get("connect_timeout"))
+	assert.Equal(t, "api", q.Get("application_name"))
+	assert.Equal(t, "Asia/Jakarta", q.Get("timezone"))
+}
+
+func TestPostgresDSNDefaultsToDisabledTLS(t *testing.T) {
+	_, dsn, err := database.DSN(config.DatabaseConfig{Kind: "postgres", Host: "db", Port: "5432", Name: "post"})
+	require.NoError(t, err)
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "disable", u.Query().Get("sslmode"))
+}
+
+func TestMysqlDSN(t *testing.T) {
+	driver, dsn, err := database.DSN(config.DatabaseConfig{
+		Kind:           "mysql",
+		Host:           "db",
+		Port:           "3306",
+		User:           "user",
+		Pass:           "password",
+		Name:           "post",
+		Timezone:       "Asia/Jakarta",
+		ConnectTimeout: 5,
+		TLS:            config.TLSConfig{Mode: config.TLSModeRequire},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mysql", driver)
+
+	c, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "db:3306", c.Addr)
+	assert.Equal(t, "post", c.DBName)
+	assert.True(t, c.ParseTime)
+	assert.True(t, c.ClientFoundRows)
+	assert.Equal(t, "Asia/Jakarta", c.Loc.String())
+	assert.Equal(t, "5s", c.Timeout.String())
+	assert.Equal(t, "skip-verify", c.TLSConfig)
+}
+
+func TestMysqlDSNMissingCA(t *testing.T) {
+	_, _, err := database.DSN(config.DatabaseConfig{
+		Kind: "mysql",
+		Host: "db",
+		Port: "3306",
+		TLS:  config.TLSConfig{Mode: config.TLSModeVerifyCA, CA: "/does/not/exist.pem"},
+	})
+
+	assert.Error(t, err)
+}
+
+func TestOpenReplicas(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Kind: "mysql",
+		Host: "primary",
+		Port: "3306",
+		Name: "post",
+		Pool: config.PoolConfig{MaxOpenConns: 3},
+		Replicas: config.ReplicasConfig{
+			Hosts: []string{"replica-1:3307", "replica-2:3308"},
+		},
+	}
+
+	replicas, err := database.OpenReplicas(cfg)
+	require.NoError(t, err)
+	require.Len(t, replicas, 2)
+	for _, replica := range replicas {
+		assert.Equal(t, 3, replica.Stats().MaxOpenConnections)
+		replica.Close()
+	}
+
+	cfg.Replicas.Hosts = append(cfg.Replicas.Hosts, "replica-3")
+	_, err = database.OpenReplicas(cfg)
+	assert.Error(t, err)
+}
+
+func TestUnknownKind(t *testing.T) {
+	_, _, err := database.DSN(config.DatabaseConfig{Kind: "oracle"})
+
+	assert.True(t, errors.Is(err, database.ErrUnknownKind))
+
+	_, err = database.Open(config.DatabaseConfig{Kind: "oracle"})
+	assert.True(t, errors.Is(err, database.ErrUnknownKind))
+}
+
+func TestSqlite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "post.db")
+	cfg := config.DatabaseConfig{Kind: "sqlite", Name: file, Pool: config.PoolConfig{MaxOpenConns: 10}}
+
+	driver, dsn, err := database.DSN(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", driver)
+	assert.Equal(t, file, dsn)
+
+	db, err := database.Open(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+}
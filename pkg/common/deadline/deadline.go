@@ -0,0 +1,17 @@
+// Package deadline bounds the usecase calls made without a deadline of their own.
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// WithDefault returns ctx bounded by timeout, unless ctx already carries a deadline such as
+// the one given to the request by its route. The returned cancel must be called in every case.
+func WithDefault(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
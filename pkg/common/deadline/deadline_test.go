@@ -0,0 +1,31 @@
+package deadline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/deadline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDefault(t *testing.T) {
+	ctx, cancel := deadline.WithDefault(context.TODO(), time.Minute)
+	defer cancel()
+
+	d, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, time.Until(d), float64(time.Second))
+}
+
+func TestWithDefaultKeepsDeadline(t *testing.T) {
+	parent, cancelParent := context.WithTimeout(context.TODO(), time.Hour)
+	defer cancelParent()
+
+	ctx, cancel := deadline.WithDefault(parent, time.Minute)
+	defer cancel()
+
+	d, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.InDelta(t, time.Hour, time.Until(d), float64(time.Second))
+}
@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+const (
+	// HeaderUserID is the header identifying the caller on whose behalf the request is made
+	HeaderUserID = "X-User-ID"
+	// HeaderRequestID is the header correlating a request across services
+	HeaderRequestID = "X-Request-ID"
+	// HeaderAPIKey is the header carrying the client's API key
+	HeaderAPIKey = "X-API-Key"
+
+	anonymousActor = "anonymous"
+
+	// localContext is the fiber local holding the context a middleware derived for the request
+	localContext = "delivery.context"
+)
+
+// SetContext makes ctx, derived from the request context by a middleware, the parent of the usecase context
+func SetContext(c *fiber.Ctx, ctx context.Context) {
+	c.Locals(localContext, ctx)
+}
+
+// Context returns the context set by SetContext, else the fasthttp request context
+func Context(c *fiber.Ctx) context.Context {
+	if ctx, ok := c.Locals(localContext).(context.Context); ok {
+		return ctx
+	}
+
+	return c.Context()
+}
+
+// RequestContext builds the context handed to the usecases for the current request. The request
+// info is copied out of the request headers, which fasthttp reuses once the request is done.
+func RequestContext(c *fiber.Ctx) context.Context {
+	actor := utils.ImmutableString(c.Get(HeaderUserID))
+	if actor == "" {
+		actor = anonymousActor
+	}
+
+	return domain.NewContextWithRequestInfo(Context(c), domain.RequestInfo{
+		Actor:     actor,
+		RequestID: utils.ImmutableString(c.Get(HeaderRequestID)),
+		IP:        utils.ImmutableString(c.IP()),
+	})
+}
@@ -0,0 +1,40 @@
+package delivery_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestContextOutlivesRequest(t *testing.T) {
+	var infos []domain.RequestInfo
+	app := fiber.New()
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		infos = append(infos, domain.RequestInfoFromContext(delivery.RequestContext(c)))
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	for i := 0; i < 20; i++ {
+		req, err := http.NewRequest("POST", "/posts", strings.NewReader(""))
+		require.NoError(t, err)
+		req.Header.Set(delivery.HeaderUserID, fmt.Sprintf("user-%d", i))
+		req.Header.Set(delivery.HeaderRequestID, fmt.Sprintf("req-%d", i))
+
+		_, err = app.Test(req, -1)
+		require.NoError(t, err)
+	}
+
+	// the request info keeps its values once fasthttp reuses the header buffers
+	require.Len(t, infos, 20)
+	for i, info := range infos {
+		assert.Equal(t, fmt.Sprintf("user-%d", i), info.Actor)
+		assert.Equal(t, fmt.Sprintf("req-%d", i), info.RequestID)
+	}
+}
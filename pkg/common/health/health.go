@@ -0,0 +1,148 @@
+// Package health runs the registered domain.HealthChecker and provides the built-in ones.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/migration"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+// Registry holds the checkers deciding whether the service is ready
+type Registry struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []domain.HealthChecker
+}
+
+// NewRegistry will create a registry giving each check up to timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+	}
+}
+
+// Register adds a checker to the readiness report
+func (r *Registry) Register(c domain.HealthChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkers = append(r.checkers, c)
+}
+
+// Check runs every checker concurrently, the report fails when one of them does
+func (r *Registry) Check(ctx context.Context) domain.HealthReport {
+	r.mu.RLock()
+	checkers := r.checkers
+	r.mu.RUnlock()
+
+	report := domain.HealthReport{
+		Status: domain.HealthStatusOK,
+		Checks: make([]domain.HealthCheckResult, len(checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c domain.HealthChecker) {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != domain.HealthStatusOK {
+			report.Status = domain.HealthStatusFail
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c domain.HealthChecker) domain.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Check(ctx)
+	res := domain.HealthCheckResult{
+		Name:      c.Name(),
+		Status:    domain.HealthStatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		res.Status = domain.HealthStatusFail
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+type checkerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewChecker will create a checker out of a plain function
+func NewChecker(name string, fn func(ctx context.Context) error) domain.HealthChecker {
+	return &checkerFunc{
+		name: name,
+		fn:   fn,
+	}
+}
+
+func (c *checkerFunc) Name() string {
+	return c.name
+}
+
+func (c *checkerFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// NewDBChecker will create a checker pinging the database
+func NewDBChecker(db *sql.DB) domain.HealthChecker {
+	return NewChecker("database", db.PingContext)
+}
+
+// MigrationStatuser reports the state of the schema migrations, *migration.Migrator implements it
+type MigrationStatuser interface {
+	Status(ctx context.Context) ([]migration.Status, error)
+}
+
+// NewMigrationChecker will create a checker failing while a migration is pending or dirty. The
+// versions this binary does not know are ignored: during a rolling deploy the new release migrates
+// the database ahead of the replicas still running the old one, which keep serving.
+func NewMigrationChecker(m MigrationStatuser) domain.HealthChecker {
+	return NewChecker("migrations", func(ctx context.Context) error {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		pending := 0
+		for _, s := range statuses {
+			if s.Unknown {
+				continue
+			}
+			if s.Dirty {
+				return fmt.Errorf("migration %d_%s is dirty", s.Version, s.Name)
+			}
+			if !s.Applied {
+				pending++
+			}
+		}
+
+		if pending > 0 {
+			return fmt.Errorf("%d migration(s) pending", pending)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/health"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/migration"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	r := health.NewRegistry(time.Second)
+	r.Register(health.NewChecker("up", func(ctx context.Context) error {
+		return nil
+	}))
+	r.Register(health.NewChecker("down", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+
+	report := r.Check(context.TODO())
+
+	assert.Equal(t, domain.HealthStatusFail, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, "up", report.Checks[0].Name)
+	assert.Equal(t, domain.HealthStatusOK, report.Checks[0].Status)
+	assert.Equal(t, "down", report.Checks[1].Name)
+	assert.Equal(t, domain.HealthStatusFail, report.Checks[1].Status)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestCheckTimeout(t *testing.T) {
+	r := health.NewRegistry(20 * time.Millisecond)
+	r.Register(health.NewChecker("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	start := time.Now()
+	report := r.Check(context.TODO())
+
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, domain.HealthStatusFail, report.Status)
+	assert.GreaterOrEqual(t, report.Checks[0].LatencyMs, int64(20))
+}
+
+type stubMigrations []migration.Status
+
+func (s stubMigrations) Status(ctx context.Context) ([]migration.Status, error) {
+	return s, nil
+}
+
+func TestMigrationChecker(t *testing.T) {
+	ctx := context.TODO()
+
+	applied := stubMigrations{{Version: 1, Name: "init", Applied: true}}
+	assert.NoError(t, health.NewMigrationChecker(applied).Check(ctx))
+
+	pending := stubMigrations{{Version: 1, Name: "init", Applied: true}, {Version: 2, Name: "next"}}
+	assert.EqualError(t, health.NewMigrationChecker(pending).Check(ctx), "1 migration(s) pending")
+
+	dirty := stubMigrations{{Version: 1, Name: "init", Applied: true, Dirty: true}}
+	assert.EqualError(t, health.NewMigrationChecker(dirty).Check(ctx), "migration 1_init is dirty")
+
+	// a newer release migrated the database further, the old one stays ready
+	ahead := stubMigrations{{Version: 1, Name: "init", Applied: true}, {Version: 2, Applied: true, Dirty: true, Unknown: true}}
+	assert.NoError(t, health.NewMigrationChecker(ahead).Check(ctx))
+
+	behindAndAhead := stubMigrations{{Version: 1, Name: "init"}, {Version: 2, Applied: true, Unknown: true}}
+	assert.EqualError(t, health.NewMigrationChecker(behindAndAhead).Check(ctx), "1 migration(s) pending")
+}
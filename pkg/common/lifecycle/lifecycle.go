@@ -0,0 +1,106 @@
+// Package lifecycle coordinates the background workers and the shutdown hooks of the service.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager runs background workers until shutdown and then the stop hooks, in reverse registration order
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	logger logrus.FieldLogger
+
+	mu       sync.Mutex
+	hooks    []hook
+	stopping bool
+}
+
+// New will create a lifecycle manager
+func New(logger logrus.FieldLogger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
+}
+
+// Go runs fn in the background. Its context is cancelled on shutdown and shutdown waits for fn to return.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopping {
+		m.logger.WithField("worker", name).Warn("not starting, shutting down")
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		fn(m.ctx)
+	}()
+}
+
+// OnStop registers fn to run on shutdown, after every worker has returned
+func (m *Manager) OnStop(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Shutdown stops the workers, waits for them until ctx is done, then runs the stop hooks.
+// The hooks run even when the workers did not stop in time, so that resources are released.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopping = true
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	m.cancel()
+
+	var problems []string
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		problems = append(problems, "background workers did not stop in time")
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", hooks[i].name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("lifecycle: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/lifecycle"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownOrder(t *testing.T) {
+	m := lifecycle.New(logrus.New())
+
+	var order []string
+	workerDone := make(chan struct{})
+
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		// stop hooks must wait for the workers
+		time.Sleep(20 * time.Millisecond)
+		order = append(order, "worker")
+		close(workerDone)
+	})
+	m.OnStop("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	m.OnStop("second", func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	err := m.Shutdown(context.TODO())
+
+	assert.NoError(t, err)
+	<-workerDone
+	assert.Equal(t, []string{"worker", "second", "first"}, order)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	m := lifecycle.New(logrus.New())
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	m.Go("stuck", func(ctx context.Context) {
+		<-stuck
+	})
+
+	closed := false
+	m.OnStop("database", func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Shutdown(ctx)
+
+	assert.Error(t, err)
+	assert.True(t, closed)
+}
+
+func TestShutdownHookError(t *testing.T) {
+	m := lifecycle.New(logrus.New())
+	m.OnStop("database", func(ctx context.Context) error {
+		return errors.New("close failed")
+	})
+
+	err := m.Shutdown(context.TODO())
+
+	assert.EqualError(t, err, "lifecycle: database: close failed")
+	// a second shutdown is a no-op
+	assert.NoError(t, m.Shutdown(context.TODO()))
+}
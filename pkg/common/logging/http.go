@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRequestIDLength bounds the X-Request-ID accepted from the client
+const maxRequestIDLength = 128
+
+// validRequestID accepts the ids made of letters, digits and -_.: only, so that a client cannot forge log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Middleware makes sure every request carries a request id, taken from X-Request-ID or generated,
+// echoes it in the response header and writes one access log line per request.
+// It has to run first so that every later log line of the request carries the id.
+func Middleware(l logrus.FieldLogger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(delivery.HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		// delivery.RequestContext reads the id back from the request header
+		c.Request().Header.Set(delivery.HeaderRequestID, id)
+		c.Set(delivery.HeaderRequestID, id)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		entry := FromContext(delivery.RequestContext(c), l).WithFields(logrus.Fields{
+			"method":     c.Method(),
+			"path":       c.Path(),
+			"status":     status,
+			"latency_ms": time.Since(start).Milliseconds(),
+			"ip":         c.IP(),
+		})
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			entry.Error("request")
+		case status >= fiber.StatusBadRequest:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,89 @@
+// Package logging builds the structured logger shared by the handlers, usecases and repositories.
+package logging
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces the value of the fields that must never reach the logs
+const Redacted = "[REDACTED]"
+
+// redactedFields are the field names holding secrets or post bodies, compared case-insensitively
+var redactedFields = map[string]bool{
+	"pass":          true,
+	"password":      true,
+	"token":         true,
+	"secret":        true,
+	"authorization": true,
+	"api_key":       true,
+	"content":       true,
+	"body":          true,
+}
+
+// redactFormatter hides the redacted fields before handing the entry to the next formatter
+type redactFormatter struct {
+	next logrus.Formatter
+}
+
+func (f redactFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	// work on a copy, the caller may log the same entry again
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		if redactedFields[strings.ToLower(key)] {
+			value = Redacted
+		}
+		data[key] = value
+	}
+
+	redacted := *entry
+	redacted.Data = data
+	return f.next.Format(&redacted)
+}
+
+// New will create a JSON logger writing to out at the level of cfg, debug forces the debug level
+func New(cfg config.LogConfig, debug bool, out io.Writer) (*logrus.Logger, error) {
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	if debug {
+		level = logrus.DebugLevel
+	}
+
+	return &logrus.Logger{
+		Out:       out,
+		Formatter: redactFormatter{next: &logrus.JSONFormatter{}},
+		Hooks:     make(logrus.LevelHooks),
+		Level:     level,
+		ExitFunc:  logrus.StandardLogger().ExitFunc,
+	}, nil
+}
+
+// FromContext returns l with the request id, the actor and the trace id carried by ctx
+func FromContext(ctx context.Context, l logrus.FieldLogger) logrus.FieldLogger {
+	fields := logrus.Fields{}
+
+	info := domain.RequestInfoFromContext(ctx)
+	if info.RequestID != "" {
+		fields["request_id"] = info.RequestID
+	}
+	if info.Actor != "" {
+		fields["actor"] = info.Actor
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields["trace_id"] = span.TraceID().String()
+	}
+
+	if len(fields) == 0 {
+		return l
+	}
+	return l.WithFields(fields)
+}
@@ -0,0 +1,117 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var res []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		res = append(res, entry)
+	}
+	return res
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(config.LogConfig{Level: "warn"}, false, &buf)
+	require.NoError(t, err)
+
+	l.Info("dropped")
+	l.WithFields(logrus.Fields{"password": "hunter2", "Content": "the whole post", "title": "kept"}).Warn("kept")
+
+	entries := lines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "kept", entries[0]["msg"])
+	assert.Equal(t, "warning", entries[0]["level"])
+	assert.Equal(t, logging.Redacted, entries[0]["password"])
+	assert.Equal(t, logging.Redacted, entries[0]["Content"])
+	assert.Equal(t, "kept", entries[0]["title"])
+}
+
+func TestNewDebug(t *testing.T) {
+	l, err := logging.New(config.LogConfig{Level: "error"}, true, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, logrus.DebugLevel, l.GetLevel())
+
+	_, err = logging.New(config.LogConfig{Level: "verbose"}, false, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(config.LogConfig{Level: "info"}, false, &buf)
+	require.NoError(t, err)
+
+	ctx := domain.NewContextWithRequestInfo(context.TODO(), domain.RequestInfo{Actor: "42", RequestID: "abc"})
+	logging.FromContext(ctx, l).Info("hello")
+
+	entries := lines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "abc", entries[0]["request_id"])
+	assert.Equal(t, "42", entries[0]["actor"])
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(config.LogConfig{Level: "info"}, false, &buf)
+	require.NoError(t, err)
+
+	var seen string
+	app := fiber.New()
+	app.Use(logging.Middleware(l))
+	app.Get("/posts", func(c *fiber.Ctx) error {
+		seen = domain.RequestInfoFromContext(delivery.RequestContext(c)).RequestID
+		logging.FromContext(delivery.RequestContext(c), l).Info("handler")
+		return c.SendStatus(http.StatusOK)
+	})
+
+	// the id sent by the client is kept
+	req, err := http.NewRequest("GET", "/posts", strings.NewReader(""))
+	require.NoError(t, err)
+	req.Header.Set(delivery.HeaderRequestID, "client-id-1")
+
+	rec, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "client-id-1", rec.Header.Get(delivery.HeaderRequestID))
+	assert.Equal(t, "client-id-1", seen)
+
+	entries := lines(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "client-id-1", entries[0]["request_id"])
+	assert.Equal(t, "client-id-1", entries[1]["request_id"])
+	assert.Equal(t, "request", entries[1]["msg"])
+	assert.Equal(t, float64(http.StatusOK), entries[1]["status"])
+
+	// a missing or malformed id is replaced by a generated one
+	buf.Reset()
+	req, err = http.NewRequest("GET", "/posts", strings.NewReader(""))
+	require.NoError(t, err)
+	req.Header.Set(delivery.HeaderRequestID, "forged\"}{")
+
+	rec, err = app.Test(req, -1)
+	require.NoError(t, err)
+	id := rec.Header.Get(delivery.HeaderRequestID)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, seen)
+	assert.Equal(t, id, lines(t, &buf)[1]["request_id"])
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// unmatchedRoute labels the requests no route matched, so that scanners cannot blow up the label set
+const unmatchedRoute = "unmatched"
+
+// Middleware records the count and the latency of every request by method, route and status
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		own := c.Route()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		// still on the route of this middleware means no other route matched
+		route := c.Route().Path
+		if c.Route() == own {
+			route = unmatchedRoute
+		}
+
+		// the labels are kept by the collectors, fiber strings point into the reused request buffer
+		labels := []string{utils.ImmutableString(c.Method()), route, strconv.Itoa(status)}
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// Handler serves the metrics gathered by g in the Prometheus exposition format
+func Handler(g prometheus.Gatherer) fiber.Handler {
+	handler := fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+
+	return func(c *fiber.Ctx) error {
+		handler(c.Context())
+		return nil
+	}
+}
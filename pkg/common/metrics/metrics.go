@@ -0,0 +1,148 @@
+// Package metrics collects the Prometheus metrics of the service. The usecase and repository
+// metrics are recorded by decorators wrapping the domain interfaces.
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes every metric of the service
+const Namespace = "app"
+
+// Metrics holds the collectors shared by the middleware and the decorators
+type Metrics struct {
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	usecaseDuration *prometheus.HistogramVec
+	usecaseErrors   *prometheus.CounterVec
+
+	repositoryDuration *prometheus.HistogramVec
+	repositoryErrors   *prometheus.CounterVec
+	repositoryRetries  *prometheus.CounterVec
+
+	cacheRequests *prometheus.CounterVec
+}
+
+// New will create the collectors and register them, with the Go runtime and process ones, on reg
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		usecaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "usecase_duration_seconds",
+			Help:      "Usecase call latency by usecase and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"usecase", "method"}),
+		usecaseErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "usecase_errors_total",
+			Help:      "Usecase calls returning an error, by usecase, method and error kind.",
+		}, []string{"usecase", "method", "error"}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "repository_duration_seconds",
+			Help:      "Repository call latency by repository and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "repository_errors_total",
+			Help:      "Repository calls returning an error, by repository, method and error kind.",
+		}, []string{"repository", "method", "error"}),
+		repositoryRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "repository_retries_total",
+			Help:      "Repository calls run again after a transient database error, by repository and method.",
+		}, []string{"repository", "method"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "cache_requests_total",
+			Help:      "Cache lookups by cache and result, hit or miss.",
+		}, []string{"cache", "result"}),
+	}
+
+	reg.MustRegister(
+		m.httpRequests,
+		m.httpDuration,
+		m.usecaseDuration,
+		m.usecaseErrors,
+		m.repositoryDuration,
+		m.repositoryErrors,
+		m.repositoryRetries,
+		m.cacheRequests,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// trackUsecase starts timing a usecase call, the returned func records it with the call's error
+func (m *Metrics) trackUsecase(usecase, method string) func(err *error) {
+	start := time.Now()
+
+	return func(err *error) {
+		m.usecaseDuration.WithLabelValues(usecase, method).Observe(time.Since(start).Seconds())
+		if *err != nil {
+			m.usecaseErrors.WithLabelValues(usecase, method, errorKind(*err)).Inc()
+		}
+	}
+}
+
+// trackRepository starts timing a repository call, the returned func records it with the call's error
+func (m *Metrics) trackRepository(repository, method string) func(err *error) {
+	start := time.Now()
+
+	return func(err *error) {
+		m.repositoryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+		if *err != nil {
+			m.repositoryErrors.WithLabelValues(repository, method, errorKind(*err)).Inc()
+		}
+	}
+}
+
+// ObserveRetry counts a repository call run again, see retry.Observer
+func (m *Metrics) ObserveRetry(repository, method string) {
+	m.repositoryRetries.WithLabelValues(repository, method).Inc()
+}
+
+// ObserveCache counts a cache lookup, see cache.Observer
+func (m *Metrics) ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// errorKind keeps the error label bounded to the domain errors
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, domain.ErrConflict):
+		return "conflict"
+	case errors.Is(err, domain.ErrBadParamInput):
+		return "bad_param"
+	case errors.Is(err, domain.ErrUnavailable):
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
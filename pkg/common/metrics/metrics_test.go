@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/metrics"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	mocks "github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostUsecase(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+
+	mockUCase := new(mocks.PostUsecase)
+	mockUCase.On("GetByID", mock.Anything, int64(1)).Return(domain.Post{ID: 1}, nil).Once()
+	mockUCase.On("GetByID", mock.Anything, int64(2)).Return(domain.Post{}, domain.ErrNotFound).Once()
+
+	u := metrics.NewPostUsecase(mockUCase, m)
+
+	res, err := u.GetByID(context.TODO(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.ID)
+
+	_, err = u.GetByID(context.TODO(), 2)
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "app_usecase_duration_seconds" {
+			assert.Equal(t, uint64(2), family.GetMetric()[0].GetHistogram().GetSampleCount())
+		}
+	}
+
+	expected := `
+# HELP app_usecase_errors_total Usecase calls returning an error, by usecase, method and error kind.
+# TYPE app_usecase_errors_total counter
+app_usecase_errors_total{error="not_found",method="GetByID",usecase="post"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "app_usecase_errors_total"))
+	mockUCase.AssertExpectations(t)
+}
+
+func TestPostRepository(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+
+	mockRepo := new(mocks.PostRepository)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(domain.ErrConflict).Once()
+
+	r := metrics.NewPostRepository(mockRepo, m)
+	err := r.Store(context.TODO(), &domain.Post{Title: "Title"})
+	assert.Equal(t, domain.ErrConflict, err)
+
+	expected := `
+# HELP app_repository_errors_total Repository calls returning an error, by repository, method and error kind.
+# TYPE app_repository_errors_total counter
+app_repository_errors_total{error="conflict",method="Store",repository="post"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "app_repository_errors_total"))
+	mockRepo.AssertExpectations(t)
+}
+
+type stubStats sql.DBStats
+
+func (s stubStats) Stats() sql.DBStats {
+	return sql.DBStats(s)
+}
+
+func TestDBStatsCollector(t *testing.T) {
+	c := metrics.NewDBStatsCollector("primary", stubStats{OpenConnections: 3, InUse: 2, WaitDuration: 2 * time.Second})
+
+	expected := `
+# HELP app_db_in_use_connections The number of connections currently in use.
+# TYPE app_db_in_use_connections gauge
+app_db_in_use_connections{db="primary"} 2
+# HELP app_db_open_connections The number of established connections both in use and idle.
+# TYPE app_db_open_connections gauge
+app_db_open_connections{db="primary"} 3
+# HELP app_db_wait_duration_seconds_total The total time blocked waiting for a new connection.
+# TYPE app_db_wait_duration_seconds_total counter
+app_db_wait_duration_seconds_total{db="primary"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"app_db_in_use_connections", "app_db_open_connections", "app_db_wait_duration_seconds_total"))
+}
+
+func TestMiddlewareAndHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+
+	e := fiber.New()
+	e.Use(m.Middleware())
+	e.Get("/metrics", metrics.Handler(reg))
+	e.Get("/posts/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/posts/1", "/posts/2", "/nothing-here"} {
+		req, err := http.NewRequest("GET", path, nil)
+		require.NoError(t, err)
+		_, err = e.Test(req, -1)
+		require.NoError(t, err)
+	}
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	require.NoError(t, err)
+	rec, err := e.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `app_http_requests_total{method="GET",route="/posts/:id",status="404"} 2`)
+	assert.Contains(t, string(body), `app_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
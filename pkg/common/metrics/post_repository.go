@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type postRepository struct {
+	next domain.PostRepository
+	m    *Metrics
+}
+
+// NewPostRepository will wrap a domain.PostRepository, recording the latency and the errors of every call
+func NewPostRepository(next domain.PostRepository, m *Metrics) domain.PostRepository {
+	return &postRepository{
+		next: next,
+		m:    m,
+	}
+}
+
+func (p *postRepository) Store(ctx context.Context, post *domain.Post) (err error) {
+	defer p.m.trackRepository("post", "Store")(&err)
+	return p.next.Store(ctx, post)
+}
+
+func (p *postRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	defer p.m.trackRepository("post", "Fetch")(&err)
+	return p.next.Fetch(ctx, cursor, num)
+}
+
+func (p *postRepository) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	defer p.m.trackRepository("post", "GetByID")(&err)
+	return p.next.GetByID(ctx, id)
+}
+
+func (p *postRepository) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	defer p.m.trackRepository("post", "GetByTitle")(&err)
+	return p.next.GetByTitle(ctx, title)
+}
+
+func (p *postRepository) Update(ctx context.Context, post *domain.Post) (err error) {
+	defer p.m.trackRepository("post", "Update")(&err)
+	return p.next.Update(ctx, post)
+}
+
+func (p *postRepository) Delete(ctx context.Context, id int64) (err error) {
+	defer p.m.trackRepository("post", "Delete")(&err)
+	return p.next.Delete(ctx, id)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type postUsecase struct {
+	next domain.PostUsecase
+	m    *Metrics
+}
+
+// NewPostUsecase will wrap a domain.PostUsecase, recording the latency and the errors of every call
+func NewPostUsecase(next domain.PostUsecase, m *Metrics) domain.PostUsecase {
+	return &postUsecase{
+		next: next,
+		m:    m,
+	}
+}
+
+func (p *postUsecase) Store(ctx context.Context, post *domain.Post) (err error) {
+	defer p.m.trackUsecase("post", "Store")(&err)
+	return p.next.Store(ctx, post)
+}
+
+func (p *postUsecase) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	defer p.m.trackUsecase("post", "Fetch")(&err)
+	return p.next.Fetch(ctx, cursor, num)
+}
+
+func (p *postUsecase) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	defer p.m.trackUsecase("post", "GetByID")(&err)
+	return p.next.GetByID(ctx, id)
+}
+
+func (p *postUsecase) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	defer p.m.trackUsecase("post", "GetByTitle")(&err)
+	return p.next.GetByTitle(ctx, title)
+}
+
+func (p *postUsecase) Update(ctx context.Context, post *domain.Post) (err error) {
+	defer p.m.trackUsecase("post", "Update")(&err)
+	return p.next.Update(ctx, post)
+}
+
+func (p *postUsecase) Delete(ctx context.Context, id int64) (err error) {
+	defer p.m.trackUsecase("post", "Delete")(&err)
+	return p.next.Delete(ctx, id)
+}
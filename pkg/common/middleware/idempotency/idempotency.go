@@ -0,0 +1,202 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// HeaderIdempotencyKey is the header carrying the client chosen idempotency key
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderReplayed is set on responses replayed from the store
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+// Record represent the stored outcome of an idempotent request
+type Record struct {
+	Key         string
+	Fingerprint string
+	// Status is zero while the request holding the key is still in flight
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// Store keeps the idempotency records shared by the middleware
+type Store interface {
+	// Reserve claims key for a new request. When the key is already claimed and not expired it
+	// returns the existing record and reserved is false.
+	Reserve(ctx context.Context, key, fingerprint string, expiresAt time.Time) (rec Record, reserved bool, err error)
+	// Get returns the record of key, found is false when there is none
+	Get(ctx context.Context, key string) (rec Record, found bool, err error)
+	// Complete stores the response of the request that reserved key
+	Complete(ctx context.Context, key string, status int, contentType string, body []byte) error
+	// Release forgets the reservation of key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Config represent the idempotency middleware configuration
+type Config struct {
+	// Methods are the HTTP methods honouring the header, it defaults to POST
+	Methods []string
+	// TTL is how long a response is kept for replay
+	TTL time.Duration
+	// Wait is how long a duplicate waits for the in-flight original before getting a 409
+	Wait time.Duration
+	// Store keeps the records, it defaults to an in-memory store
+	Store Store
+	// Now returns the current time, it defaults to time.Now
+	Now func() time.Time
+	// Logger reports the store errors, it defaults to the logrus standard logger
+	Logger logrus.FieldLogger
+}
+
+const pollInterval = 50 * time.Millisecond
+
+// New creates a middleware honouring the Idempotency-Key header
+func New(cfg Config) fiber.Handler {
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost}
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+
+	methods := map[string]bool{}
+	for _, method := range cfg.Methods {
+		methods[method] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get(HeaderIdempotencyKey)
+		if idempotencyKey == "" || !methods[c.Method()] {
+			return c.Next()
+		}
+
+		ctx := c.Context()
+		key := scope(c, idempotencyKey)
+		fingerprint := fingerprint(c)
+
+		rec, reserved, err := cfg.Store.Reserve(ctx, key, fingerprint, cfg.Now().Add(cfg.TTL))
+		if err != nil {
+			logging.FromContext(delivery.RequestContext(c), cfg.Logger).WithError(err).Error("idempotency store")
+			return respondError(c, http.StatusInternalServerError)
+		}
+
+		if !reserved {
+			return replay(c, cfg, key, fingerprint, rec)
+		}
+
+		err = c.Next()
+		status := c.Response().StatusCode()
+		if err != nil || status >= http.StatusInternalServerError {
+			// failures are not remembered so the client can safely retry them
+			errRelease := cfg.Store.Release(ctx, key)
+			if errRelease != nil {
+				logging.FromContext(delivery.RequestContext(c), cfg.Logger).WithError(errRelease).Error("idempotency store")
+			}
+			return err
+		}
+
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		errComplete := cfg.Store.Complete(ctx, key, status, contentType, body)
+		if errComplete != nil {
+			logging.FromContext(delivery.RequestContext(c), cfg.Logger).WithError(errComplete).Error("idempotency store")
+		}
+
+		return nil
+	}
+}
+
+func replay(c *fiber.Ctx, cfg Config, key, fingerprint string, rec Record) (err error) {
+	if rec.Fingerprint != fingerprint {
+		return respondError(c, http.StatusUnprocessableEntity)
+	}
+
+	// wait for the original request to finish, no longer than the request itself may run
+	ctx := delivery.Context(c)
+	deadline := cfg.Now().Add(cfg.Wait)
+	for rec.Status == 0 {
+		if !cfg.Now().Before(deadline) {
+			return respondError(c, http.StatusConflict)
+		}
+		select {
+		case <-ctx.Done():
+			return respondError(c, contextStatus(ctx.Err()))
+		case <-time.After(pollInterval):
+		}
+
+		var found bool
+		rec, found, err = cfg.Store.Get(ctx, key)
+		if err != nil {
+			logging.FromContext(delivery.RequestContext(c), cfg.Logger).WithError(err).Error("idempotency store")
+			return respondError(c, http.StatusInternalServerError)
+		}
+		if !found {
+			// the original failed and released the key
+			return respondError(c, http.StatusConflict)
+		}
+	}
+
+	c.Set(HeaderReplayed, "true")
+	if rec.ContentType != "" {
+		c.Set(fiber.HeaderContentType, rec.ContentType)
+	}
+	c.Status(rec.Status)
+	return c.Send(rec.Body)
+}
+
+// contextStatus is the status of a request whose context ended: its deadline passed,
+// or the server is shutting down
+func contextStatus(err error) int {
+	if err == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusServiceUnavailable
+}
+
+// scope keeps the keys of different clients and routes apart
+func scope(c *fiber.Ctx, idempotencyKey string) string {
+	h := sha256.New()
+	for _, part := range []string{c.Method(), c.Path(), c.Get(delivery.HeaderAPIKey), c.Get(delivery.HeaderUserID), idempotencyKey} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint identifies the request payload a key was first used with
+func fingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte(c.OriginalURL()))
+	h.Write(c.Body())
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func respondError(c *fiber.Ctx, status int) error {
+	c.Status(status)
+	return c.JSON(fiber.Map{
+		"error":   status,
+		"message": http.StatusText(status),
+	})
+}
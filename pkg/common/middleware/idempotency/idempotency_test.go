@@ -0,0 +1,188 @@
+package idempotency_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/idempotency"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/timeout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest(t *testing.T, body, key string) *http.Request {
+	req, err := http.NewRequest("POST", "/posts", strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotency.HeaderIdempotencyKey, key)
+
+	return req
+}
+
+func TestReplay(t *testing.T) {
+	var calls int32
+	app := fiber.New()
+	app.Use(idempotency.New(idempotency.Config{TTL: time.Hour}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		n := atomic.AddInt32(&calls, 1)
+		c.Status(http.StatusCreated)
+		return c.JSON(fiber.Map{"id": n})
+	})
+
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	first, _ := ioutil.ReadAll(rec.Body)
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+
+	rec, err = app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	replayed, _ := ioutil.ReadAll(rec.Body)
+
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+	assert.Equal(t, "true", rec.Header.Get(idempotency.HeaderReplayed))
+	assert.Equal(t, "application/json", rec.Header.Get("Content-Type"))
+	assert.Equal(t, first, replayed)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMismatchedBody(t *testing.T) {
+	app := fiber.New()
+	app.Use(idempotency.New(idempotency.Config{TTL: time.Hour}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+
+	rec, err = app.Test(newRequest(t, `{"title":"Other"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.StatusCode)
+}
+
+func TestInFlightDuplicate(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	app := fiber.New()
+	app.Use(idempotency.New(idempotency.Config{TTL: time.Hour}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	done := make(chan int)
+	go func() {
+		rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+		require.NoError(t, err)
+		done <- rec.StatusCode
+	}()
+	<-started
+
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rec.StatusCode)
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, <-done)
+}
+
+func TestInFlightDuplicateWaits(t *testing.T) {
+	started := make(chan struct{})
+	app := fiber.New()
+	app.Use(idempotency.New(idempotency.Config{TTL: time.Hour, Wait: 2 * time.Second}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	go func() {
+		_, _ = app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	}()
+	<-started
+
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+	assert.Equal(t, "true", rec.Header.Get(idempotency.HeaderReplayed))
+}
+
+func TestInFlightDuplicateStopsAtDeadline(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	app := fiber.New()
+	app.Use(timeout.New(timeout.Config{Timeout: 100 * time.Millisecond}))
+	app.Use(idempotency.New(idempotency.Config{TTL: time.Hour, Wait: 5 * time.Second}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	done := make(chan int)
+	go func() {
+		rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+		require.NoError(t, err)
+		done <- rec.StatusCode
+	}()
+	<-started
+
+	// the duplicate gives up with its request, long before the wait is over
+	begin := time.Now()
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.StatusCode)
+	assert.Less(t, int64(time.Since(begin)), int64(time.Second))
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, <-done)
+}
+
+func TestFailureIsNotRemembered(t *testing.T) {
+	var calls int32
+	app := fiber.New()
+	app.Use(idempotency.New(idempotency.Config{TTL: time.Hour}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return c.SendStatus(http.StatusInternalServerError)
+		}
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, rec.StatusCode)
+
+	rec, err = app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+	assert.Empty(t, rec.Header.Get(idempotency.HeaderReplayed))
+}
+
+func TestExpiredKey(t *testing.T) {
+	app := fiber.New()
+	app.Use(idempotency.New(idempotency.Config{
+		TTL: time.Minute,
+		// every record is stored already past its ttl
+		Now: func() time.Time { return time.Now().Add(-2 * time.Minute) },
+	}))
+	app.Post("/posts", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	rec, err := app.Test(newRequest(t, `{"title":"Title"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+
+	rec, err = app.Test(newRequest(t, `{"title":"Other"}`, "key-1"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.StatusCode)
+	assert.Empty(t, rec.Header.Get(idempotency.HeaderReplayed))
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the expired records are dropped
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore will create a Store keeping the records in the process memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		records: map[string]Record{},
+		now:     time.Now,
+	}
+}
+
+func (m *memoryStore) Reserve(ctx context.Context, key, fingerprint string, expiresAt time.Time) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	if rec, ok := m.records[key]; ok && rec.ExpiresAt.After(now) {
+		return rec, false, nil
+	}
+
+	m.records[key] = Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   expiresAt,
+	}
+
+	return Record{}, true, nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.records[key]
+	if ok && !rec.ExpiresAt.After(m.now()) {
+		return Record{}, false, nil
+	}
+
+	return rec, ok, nil
+}
+
+func (m *memoryStore) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.records[key]
+	if !ok {
+		return nil
+	}
+
+	rec.Status = status
+	rec.ContentType = contentType
+	rec.Body = body
+	m.records[key] = rec
+
+	return nil
+}
+
+func (m *memoryStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
+
+// sweep drops the expired records, at most once per sweepInterval
+func (m *memoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, rec := range m.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(m.records, key)
+		}
+	}
+}
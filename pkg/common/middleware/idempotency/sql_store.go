@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type sqlStore struct {
+	DB        *sql.DB
+	Dialect   repository.Dialect
+	Logger    logrus.FieldLogger
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewSQLStore will create a Store sharing the records through the idempotency_key table
+// of the sql engine spoken by dialect
+func NewSQLStore(db *sql.DB, dialect repository.Dialect, logger logrus.FieldLogger) Store {
+	return &sqlStore{
+		DB:      db,
+		Dialect: dialect,
+		Logger:  logger,
+	}
+}
+
+// table is the qualified name of the record table
+func (s *sqlStore) table() string {
+	return s.Dialect.Table("idempotency_key")
+}
+
+func (s *sqlStore) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := s.DB.ExecContext(ctx, repository.Rebind(s.Dialect, query), args...)
+	return err
+}
+
+func (s *sqlStore) Reserve(ctx context.Context, key, fingerprint string, expiresAt time.Time) (rec Record, reserved bool, err error) {
+	now := time.Now()
+	s.sweep(ctx, now)
+
+	// an expired record is taken over as if it never existed
+	err = s.exec(ctx, `DELETE FROM `+s.table()+` WHERE idem_key = ? AND expires_at <= ?`, key, toMillis(now))
+	if err != nil {
+		return
+	}
+
+	query := `INSERT INTO ` + s.table() + ` (idem_key, fingerprint, status, content_type, body, expires_at)
+				VALUES (?, ?, 0, '', NULL, ?)`
+
+	err = s.Dialect.TranslateError(s.exec(ctx, query, key, fingerprint, toMillis(expiresAt)))
+	if err == nil {
+		return Record{}, true, nil
+	}
+	if err != domain.ErrConflict {
+		return
+	}
+
+	rec, _, err = s.Get(ctx, key)
+	return rec, false, err
+}
+
+func (s *sqlStore) Get(ctx context.Context, key string) (rec Record, found bool, err error) {
+	query := `SELECT idem_key, fingerprint, status, content_type, body, expires_at
+				FROM ` + s.table() + `
+				WHERE idem_key = ? AND expires_at > ?`
+
+	var expiresAt int64
+	err = s.DB.QueryRowContext(ctx, repository.Rebind(s.Dialect, query), key, toMillis(time.Now())).Scan(
+		&rec.Key,
+		&rec.Fingerprint,
+		&rec.Status,
+		&rec.ContentType,
+		&rec.Body,
+		&expiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return
+	}
+
+	rec.ExpiresAt = fromMillis(expiresAt)
+	return rec, true, nil
+}
+
+func (s *sqlStore) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	return s.exec(ctx, `UPDATE `+s.table()+` SET status = ?, content_type = ?, body = ? WHERE idem_key = ?`,
+		status, contentType, body, key)
+}
+
+func (s *sqlStore) Release(ctx context.Context, key string) error {
+	return s.exec(ctx, `DELETE FROM `+s.table()+` WHERE idem_key = ?`, key)
+}
+
+// sweep deletes the expired records, at most once per sweepInterval from each replica
+func (s *sqlStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	err := s.exec(ctx, `DELETE FROM `+s.table()+` WHERE expires_at <= ?`, toMillis(now))
+	if err != nil {
+		s.Logger.WithError(err).Error("sweeping expired records")
+	}
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,68 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/idempotency"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStore(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		t.Run(kind, func(t *testing.T) {
+			db, dialect := repositorytest.OpenDatabase(t, kind)
+			s := idempotency.NewSQLStore(db, dialect, logrus.New())
+			expiresAt := time.Now().Add(time.Hour)
+
+			_, reserved, err := s.Reserve(context.TODO(), "key-1", "fingerprint", expiresAt)
+			require.NoError(t, err)
+			assert.True(t, reserved)
+
+			rec, reserved, err := s.Reserve(context.TODO(), "key-1", "other", expiresAt)
+			require.NoError(t, err)
+			assert.False(t, reserved)
+			assert.Equal(t, "fingerprint", rec.Fingerprint)
+			assert.Zero(t, rec.Status)
+
+			require.NoError(t, s.Complete(context.TODO(), "key-1", 201, "application/json", []byte(`{"id":1}`)))
+			rec, found, err := s.Get(context.TODO(), "key-1")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, 201, rec.Status)
+			assert.Equal(t, "application/json", rec.ContentType)
+			assert.Equal(t, []byte(`{"id":1}`), rec.Body)
+			assert.Equal(t, expiresAt.Truncate(time.Millisecond), rec.ExpiresAt.Truncate(time.Millisecond))
+
+			require.NoError(t, s.Release(context.TODO(), "key-1"))
+			_, found, err = s.Get(context.TODO(), "key-1")
+			require.NoError(t, err)
+			assert.False(t, found)
+		})
+	}
+}
+
+func TestSQLStoreTakesOverExpired(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		t.Run(kind, func(t *testing.T) {
+			db, dialect := repositorytest.OpenDatabase(t, kind)
+			s := idempotency.NewSQLStore(db, dialect, logrus.New())
+
+			_, reserved, err := s.Reserve(context.TODO(), "key-1", "fingerprint", time.Now().Add(-time.Second))
+			require.NoError(t, err)
+			require.True(t, reserved)
+
+			_, found, err := s.Get(context.TODO(), "key-1")
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			_, reserved, err = s.Reserve(context.TODO(), "key-1", "other", time.Now().Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, reserved)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type counter struct {
+	windowStart time.Time
+	expiresAt   time.Time
+	current     int64
+	previous    int64
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+// NewMemoryStore will create a Store keeping the counters in the process memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		counters: map[string]*counter{},
+	}
+}
+
+func (m *memoryStore) Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration) (current, previous int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(windowStart, window)
+
+	c, ok := m.counters[key]
+	if !ok {
+		c = &counter{windowStart: windowStart}
+		m.counters[key] = c
+	}
+
+	switch {
+	case c.windowStart.Add(window).Equal(windowStart):
+		// the window rolled over, the current one becomes the previous
+		c.windowStart, c.previous, c.current = windowStart, c.current, 0
+	case c.windowStart.Before(windowStart):
+		c.windowStart, c.previous, c.current = windowStart, 0, 0
+	}
+
+	// a counter is still read as the previous window during the next one
+	c.expiresAt = windowStart.Add(2 * window)
+	c.current++
+	return c.current, c.previous, nil
+}
+
+// sweep drops the counters that can no longer affect a decision, at most once per window
+func (m *memoryStore) sweep(windowStart time.Time, window time.Duration) {
+	if windowStart.Sub(m.lastSweep) < window {
+		return
+	}
+
+	for key, c := range m.counters {
+		if !c.expiresAt.After(windowStart) {
+			delete(m.counters, key)
+		}
+	}
+	m.lastSweep = windowStart
+}
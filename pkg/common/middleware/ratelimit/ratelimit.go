@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Standard rate limit response headers
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Store keeps the request counters shared by the limiter
+type Store interface {
+	// Increment adds a hit to key in the window starting at windowStart and returns
+	// the hits counted so far in that window and in the window right before it
+	Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration) (current, previous int64, err error)
+}
+
+// KeyFunc extracts the client identity a request is limited by
+type KeyFunc func(c *fiber.Ctx) string
+
+// Config represent the limit applied to one route group
+type Config struct {
+	// Name namespaces the counters of this group in the store
+	Name string
+	// Limit is the number of requests allowed per Window
+	Limit int64
+	// Window is the length of the sliding window
+	Window time.Duration
+	// KeyFunc identifies the client, it defaults to KeyByIP
+	KeyFunc KeyFunc
+	// Store keeps the counters, it defaults to an in-memory store
+	Store Store
+	// Now returns the current time, it defaults to time.Now
+	Now func() time.Time
+	// Logger reports the store errors, it defaults to the logrus standard logger
+	Logger logrus.FieldLogger
+}
+
+// New creates a sliding window rate limiter middleware for the given config
+func New(cfg Config) fiber.Handler {
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = KeyByIP
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+
+	limit := strconv.FormatInt(cfg.Limit, 10)
+
+	return func(c *fiber.Ctx) error {
+		now := cfg.Now()
+		windowStart := now.Truncate(cfg.Window)
+		key := cfg.Name + "|" + cfg.KeyFunc(c)
+
+		current, previous, err := cfg.Store.Increment(c.Context(), key, windowStart, cfg.Window)
+		if err != nil {
+			// fail open, an unavailable store must not take the API down with it
+			logging.FromContext(delivery.RequestContext(c), cfg.Logger).WithError(err).Error("rate limit store")
+			return c.Next()
+		}
+
+		// weight the previous window by how much of it still overlaps the sliding window
+		elapsed := now.Sub(windowStart)
+		weight := 1 - float64(elapsed)/float64(cfg.Window)
+		estimated := int64(math.Floor(float64(previous)*weight)) + current
+
+		remaining := cfg.Limit - estimated
+		if remaining < 0 {
+			remaining = 0
+		}
+		reset := int64(math.Ceil(windowStart.Add(cfg.Window).Sub(now).Seconds()))
+
+		c.Set(HeaderLimit, limit)
+		c.Set(HeaderRemaining, strconv.FormatInt(remaining, 10))
+		c.Set(HeaderReset, strconv.FormatInt(reset, 10))
+
+		if estimated > cfg.Limit {
+			c.Set(HeaderRetryAfter, strconv.FormatInt(reset, 10))
+			c.Status(http.StatusTooManyRequests)
+			return c.JSON(fiber.Map{
+				"error":   http.StatusTooManyRequests,
+				"message": http.StatusText(http.StatusTooManyRequests),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// KeyByIP limits requests per client IP
+func KeyByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// KeyByUserID limits requests per user, falling back to the client IP for anonymous requests
+func KeyByUserID(c *fiber.Ctx) string {
+	if userID := c.Get(delivery.HeaderUserID); userID != "" {
+		return "user:" + userID
+	}
+
+	return KeyByIP(c)
+}
+
+// KeyByAPIKey limits requests per API key, falling back to the client IP when none is given
+func KeyByAPIKey(c *fiber.Ctx) string {
+	if apiKey := c.Get(delivery.HeaderAPIKey); apiKey != "" {
+		return "api_key:" + apiKey
+	}
+
+	return KeyByIP(c)
+}
+
+// KeyFuncByName returns the KeyFunc configured by name, one of "ip", "user" or "api_key"
+func KeyFuncByName(name string) (KeyFunc, error) {
+	switch name {
+	case "", "ip":
+		return KeyByIP, nil
+	case "user":
+		return KeyByUserID, nil
+	case "api_key":
+		return KeyByAPIKey, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", name)
+	}
+}
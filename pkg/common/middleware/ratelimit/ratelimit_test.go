@@ -0,0 +1,133 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration) (int64, int64, error) {
+	return 0, 0, errors.New("Unexpected Error")
+}
+
+func newApp(cfg ratelimit.Config) *fiber.App {
+	app := fiber.New()
+	app.Use("/posts", ratelimit.New(cfg))
+	app.Get("/posts", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	return app
+}
+
+func TestLimit(t *testing.T) {
+	now := time.Date(2020, 10, 20, 10, 0, 0, 0, time.UTC)
+	app := newApp(ratelimit.Config{
+		Name:   "/posts",
+		Limit:  2,
+		Window: time.Minute,
+		Now:    func() time.Time { return now },
+	})
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "/posts", nil)
+		assert.NoError(t, err)
+
+		rec, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rec.StatusCode)
+		assert.Equal(t, "2", rec.Header.Get(ratelimit.HeaderLimit))
+		assert.Equal(t, "60", rec.Header.Get(ratelimit.HeaderReset))
+	}
+
+	req, err := http.NewRequest("GET", "/posts", nil)
+	assert.NoError(t, err)
+
+	rec, err := app.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.StatusCode)
+	assert.Equal(t, "0", rec.Header.Get(ratelimit.HeaderRemaining))
+	assert.Equal(t, "60", rec.Header.Get(ratelimit.HeaderRetryAfter))
+}
+
+func TestLimitSlidingWindow(t *testing.T) {
+	now := time.Date(2020, 10, 20, 10, 0, 0, 0, time.UTC)
+	app := newApp(ratelimit.Config{
+		Name:   "/posts",
+		Limit:  4,
+		Window: time.Minute,
+		Now:    func() time.Time { return now },
+	})
+
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("GET", "/posts", nil)
+		_, err := app.Test(req, -1)
+		require.NoError(t, err)
+	}
+
+	// halfway through the next window half of the previous hits still count
+	now = now.Add(90 * time.Second)
+	req, _ := http.NewRequest("GET", "/posts", nil)
+	rec, err := app.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+	assert.Equal(t, "1", rec.Header.Get(ratelimit.HeaderRemaining))
+	assert.Equal(t, "30", rec.Header.Get(ratelimit.HeaderReset))
+}
+
+func TestLimitPerKey(t *testing.T) {
+	app := newApp(ratelimit.Config{
+		Name:    "/posts",
+		Limit:   1,
+		Window:  time.Minute,
+		KeyFunc: ratelimit.KeyByAPIKey,
+	})
+
+	for _, apiKey := range []string{"first", "second"} {
+		req, _ := http.NewRequest("GET", "/posts", nil)
+		req.Header.Set("X-API-Key", apiKey)
+
+		rec, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rec.StatusCode)
+	}
+}
+
+func TestLimitStoreFailure(t *testing.T) {
+	app := newApp(ratelimit.Config{
+		Name:   "/posts",
+		Limit:  1,
+		Window: time.Minute,
+		Store:  failingStore{},
+	})
+
+	req, _ := http.NewRequest("GET", "/posts", nil)
+	rec, err := app.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+	assert.Empty(t, rec.Header.Get(ratelimit.HeaderLimit))
+}
+
+func TestKeyFuncByName(t *testing.T) {
+	for _, name := range []string{"", "ip", "user", "api_key"} {
+		_, err := ratelimit.KeyFuncByName(name)
+		assert.NoError(t, err)
+	}
+
+	_, err := ratelimit.KeyFuncByName("cookie")
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/sirupsen/logrus"
+)
+
+type sqlStore struct {
+	DB        *sql.DB
+	Dialect   repository.Dialect
+	Logger    logrus.FieldLogger
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewSQLStore will create a Store sharing the counters through the rate_limit_counter table
+// of the sql engine spoken by dialect
+func NewSQLStore(db *sql.DB, dialect repository.Dialect, logger logrus.FieldLogger) Store {
+	return &sqlStore{
+		DB:      db,
+		Dialect: dialect,
+		Logger:  logger,
+	}
+}
+
+// table is the qualified name of the counter table
+func (s *sqlStore) table() string {
+	return s.Dialect.Table("rate_limit_counter")
+}
+
+func (s *sqlStore) Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration) (current, previous int64, err error) {
+	current, err = s.increment(ctx, key, windowStart, window)
+	if err != nil {
+		return
+	}
+
+	query := `SELECT hits FROM ` + s.table() + ` WHERE bucket = ? AND window_start = ?`
+
+	err = s.DB.QueryRowContext(ctx, repository.Rebind(s.Dialect, query), key, toMillis(windowStart.Add(-window))).Scan(&previous)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	s.sweep(ctx, windowStart, window)
+	return
+}
+
+// increment adds the hit and reads the counter back within a transaction, the counter row stays
+// locked from the update to the commit so that no other hit is counted in between
+func (s *sqlStore) increment(ctx context.Context, key string, windowStart time.Time, window time.Duration) (current int64, err error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// the first hit of the window creates its counter, the update below counts it
+	query := `INSERT INTO ` + s.table() + ` (bucket, window_start, hits, expires_at) VALUES (?, ?, 0, ?) ` +
+		s.Dialect.Upsert([]string{"bucket", "window_start"}, []string{"expires_at"})
+
+	_, err = tx.ExecContext(ctx, repository.Rebind(s.Dialect, query), key, toMillis(windowStart), toMillis(windowStart.Add(2*window)))
+	if err != nil {
+		return
+	}
+
+	query = `UPDATE ` + s.table() + ` SET hits = hits + 1 WHERE bucket = ? AND window_start = ?`
+
+	_, err = tx.ExecContext(ctx, repository.Rebind(s.Dialect, query), key, toMillis(windowStart))
+	if err != nil {
+		return
+	}
+
+	query = `SELECT hits FROM ` + s.table() + ` WHERE bucket = ? AND window_start = ?`
+
+	err = tx.QueryRowContext(ctx, repository.Rebind(s.Dialect, query), key, toMillis(windowStart)).Scan(&current)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// sweep deletes the expired counters, at most once per window from each replica
+func (s *sqlStore) sweep(ctx context.Context, windowStart time.Time, window time.Duration) {
+	s.mu.Lock()
+	if windowStart.Sub(s.lastSweep) < window {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = windowStart
+	s.mu.Unlock()
+
+	query := `DELETE FROM ` + s.table() + ` WHERE expires_at <= ?`
+
+	_, err := s.DB.ExecContext(ctx, repository.Rebind(s.Dialect, query), toMillis(windowStart))
+	if err != nil {
+		s.Logger.WithError(err).Error("sweeping expired records")
+	}
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
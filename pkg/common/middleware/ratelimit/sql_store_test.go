@@ -0,0 +1,74 @@
+package ratelimit_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/ratelimit"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStoreIncrement(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		t.Run(kind, func(t *testing.T) {
+			db, dialect := repositorytest.OpenDatabase(t, kind)
+			s := ratelimit.NewSQLStore(db, dialect, logrus.New())
+
+			windowStart := time.Unix(120, 0)
+			for i := int64(1); i <= 3; i++ {
+				current, previous, err := s.Increment(context.TODO(), "posts|ip:0.0.0.0", windowStart, time.Minute)
+				require.NoError(t, err)
+				assert.Equal(t, i, current)
+				assert.Equal(t, int64(0), previous)
+			}
+
+			current, previous, err := s.Increment(context.TODO(), "posts|ip:0.0.0.0", windowStart.Add(time.Minute), time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), current)
+			assert.Equal(t, int64(3), previous)
+
+			// another key counts apart
+			current, _, err = s.Increment(context.TODO(), "posts|ip:10.0.0.1", windowStart.Add(time.Minute), time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), current)
+		})
+	}
+}
+
+func TestSQLStoreConcurrentIncrements(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		t.Run(kind, func(t *testing.T) {
+			db, dialect := repositorytest.OpenDatabase(t, kind)
+			s := ratelimit.NewSQLStore(db, dialect, logrus.New())
+
+			// every hit reads its own count back, no two hits see the same one
+			const hits = 20
+			var mu sync.Mutex
+			seen := map[int64]bool{}
+			var wg sync.WaitGroup
+			for i := 0; i < hits; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					current, _, err := s.Increment(context.TODO(), "posts|ip:0.0.0.0", time.Unix(120, 0), time.Minute)
+					assert.NoError(t, err)
+
+					mu.Lock()
+					defer mu.Unlock()
+					assert.False(t, seen[current], fmt.Sprint(current))
+					seen[current] = true
+				}()
+			}
+			wg.Wait()
+
+			assert.Len(t, seen, hits)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Package timeout gives every request a context bounded by the timeout of its route.
+//
+// The context is not cancelled when the client disconnects. fasthttp reads the connection itself
+// and never reports a peer closing it while a request is handled, and reading the connection from
+// here would consume the next pipelined request. The deadline is what bounds the work of an
+// abandoned request.
+package timeout
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+)
+
+// Config represent the request timeouts
+type Config struct {
+	// Timeout bounds the requests no entry of Routes matches, zero leaves them unbounded
+	Timeout time.Duration
+	// Routes maps a path prefix to the timeout of its requests, the longest matching prefix wins.
+	// A prefix matches whole path segments: /posts matches /posts and /posts/1, not /postings.
+	Routes map[string]time.Duration
+}
+
+// timeoutFor returns the timeout of the longest prefix of path found in Routes, else Timeout
+func (cfg Config) timeoutFor(path string) time.Duration {
+	timeout, longest := cfg.Timeout, -1
+	for prefix, d := range cfg.Routes {
+		if matches(path, prefix) && len(prefix) > longest {
+			timeout, longest = d, len(prefix)
+		}
+	}
+
+	return timeout
+}
+
+// matches reports whether prefix is path or one of its leading segments
+func matches(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// New creates a middleware deriving the context of every request, as returned by
+// delivery.RequestContext, with the deadline of its route. The context is also cancelled
+// when the server shuts down.
+func New(cfg Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout := cfg.timeoutFor(c.Path())
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(delivery.Context(c), timeout)
+		defer cancel()
+
+		delivery.SetContext(c, ctx)
+		return c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package timeout_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/timeout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	app := fiber.New()
+	app.Use(timeout.New(timeout.Config{
+		Timeout: time.Hour,
+		Routes: map[string]time.Duration{
+			"/posts":        time.Minute,
+			"/posts/export": 10 * time.Minute,
+			"/post":         time.Second,
+		},
+	}))
+
+	budgets := map[string]time.Duration{}
+	handler := func(c *fiber.Ctx) error {
+		deadline, ok := delivery.RequestContext(c).Deadline()
+		require.True(t, ok)
+		budgets[c.Route().Path] = time.Until(deadline)
+		return c.SendStatus(http.StatusOK)
+	}
+	app.Get("/posts", handler)
+	app.Get("/posts/export", handler)
+	app.Get("/posts/:id", handler)
+	app.Get("/postings", handler)
+	app.Get("/audit", handler)
+
+	for _, path := range []string{"/posts", "/posts/export", "/posts/12", "/postings", "/audit"} {
+		req, err := http.NewRequest("GET", path, strings.NewReader(""))
+		require.NoError(t, err)
+		_, err = app.Test(req, -1)
+		require.NoError(t, err)
+	}
+
+	assert.InDelta(t, time.Minute, budgets["/posts"], float64(time.Second))
+	assert.InDelta(t, 10*time.Minute, budgets["/posts/export"], float64(time.Second))
+	assert.InDelta(t, time.Minute, budgets["/posts/:id"], float64(time.Second))
+	assert.InDelta(t, time.Hour, budgets["/postings"], float64(time.Second))
+	assert.InDelta(t, time.Hour, budgets["/audit"], float64(time.Second))
+}
+
+func TestTimeoutUnbounded(t *testing.T) {
+	app := fiber.New()
+	app.Use(timeout.New(timeout.Config{}))
+
+	var bounded bool
+	app.Get("/posts", func(c *fiber.Ctx) error {
+		_, bounded = delivery.RequestContext(c).Deadline()
+		return c.SendStatus(http.StatusOK)
+	})
+
+	req, err := http.NewRequest("GET", "/posts", strings.NewReader(""))
+	require.NoError(t, err)
+	_, err = app.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.False(t, bounded)
+}
@@ -0,0 +1,162 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// lockName identifies the advisory lock held while migrating
+const lockName = "schema_migrations"
+
+// lockTimeout is how many seconds mysql waits for the advisory lock
+const lockTimeout = 300
+
+type dialect interface {
+	// table is the qualified name of the version tracking table
+	table() string
+	createTable() string
+	// tableExists is a query counting the version tracking tables, 0 or 1
+	tableExists() string
+	placeholder(n int) string
+	// transactional reports whether DDL statements can be rolled back
+	transactional() bool
+	lock(ctx context.Context, conn *sql.Conn) error
+	unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+func dialectFor(kind string) (dialect, error) {
+	switch kind {
+	case "postgres":
+		return postgresDialect{}, nil
+	case "mysql":
+		return mysqlDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	default:
+		return nil, fmt.Errorf("migrations are not supported for database kind %q", kind)
+	}
+}
+
+type postgresDialect struct{}
+
+// postgresLockKey is the application wide pg_advisory_lock key guarding the migrations
+const postgresLockKey = 7031553297061486387
+
+func (postgresDialect) table() string {
+	return "public.schema_migrations"
+}
+
+func (postgresDialect) createTable() string {
+	return `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+				version bigint PRIMARY KEY,
+				name character varying(255) NOT NULL,
+				dirty boolean NOT NULL,
+				applied_at timestamp(0) without time zone NOT NULL
+			)`
+}
+
+func (postgresDialect) tableExists() string {
+	return `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public' AND table_name = 'schema_migrations'`
+}
+
+func (postgresDialect) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) transactional() bool {
+	return true
+}
+
+func (postgresDialect) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, int64(postgresLockKey))
+	return err
+}
+
+func (postgresDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, int64(postgresLockKey))
+	return err
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) table() string {
+	return "schema_migrations"
+}
+
+func (mysqlDialect) createTable() string {
+	return `CREATE TABLE IF NOT EXISTS schema_migrations (
+				version bigint(20) NOT NULL,
+				name varchar(255) NOT NULL,
+				dirty tinyint(1) NOT NULL,
+				applied_at datetime NOT NULL,
+				PRIMARY KEY (version)
+			)`
+}
+
+func (mysqlDialect) tableExists() string {
+	return `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_migrations'`
+}
+
+func (mysqlDialect) placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) transactional() bool {
+	return false
+}
+
+func (mysqlDialect) lock(ctx context.Context, conn *sql.Conn) error {
+	var acquired sql.NullInt64
+	err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, lockName, lockTimeout).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("could not acquire the %s lock within %d seconds", lockName, lockTimeout)
+	}
+
+	return nil
+}
+
+func (mysqlDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, lockName)
+	return err
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) table() string {
+	return "schema_migrations"
+}
+
+func (sqliteDialect) createTable() string {
+	return `CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				dirty BOOLEAN NOT NULL,
+				applied_at DATETIME NOT NULL
+			)`
+}
+
+func (sqliteDialect) tableExists() string {
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+}
+
+func (sqliteDialect) placeholder(n int) string {
+	return "?"
+}
+
+func (sqliteDialect) transactional() bool {
+	return true
+}
+
+// lock is a no-op, the database file is only written by one migration transaction at a time
+func (sqliteDialect) lock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+func (sqliteDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
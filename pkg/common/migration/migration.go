@@ -0,0 +1,411 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Migration represent one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status represent whether a migration has been applied to the database
+type Status struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	Dirty     bool      `json:"dirty"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+	// Unknown marks a version applied to the database that this binary has no migration for,
+	// such as one applied by a newer release
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Migrator applies the migrations of one database kind and tracks them in schema_migrations
+type Migrator struct {
+	DB         *sql.DB
+	Logger     logrus.FieldLogger
+	dialect    dialect
+	migrations []Migration
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// New will create a Migrator for the given database kind reading the migrations from source
+func New(db *sql.DB, kind string, source fs.FS, logger logrus.FieldLogger) (*Migrator, error) {
+	d, err := dialectFor(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := load(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		DB:         db,
+		Logger:     logger,
+		dialect:    d,
+		migrations: migrations,
+	}, nil
+}
+
+func load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order and returns the applied ones
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.knownStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = checkClean(statuses); err != nil {
+			return err
+		}
+
+		for i, s := range statuses {
+			if s.Applied {
+				continue
+			}
+
+			mig := m.migrations[i]
+			m.Logger.WithFields(logrus.Fields{"version": mig.Version, "name": mig.Name}).Info("applying migration")
+			if err = m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			applied = append(applied, mig)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// Down reverts the given number of most recently applied migrations and returns the reverted ones
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.knownStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = checkClean(statuses); err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+			if !statuses[i].Applied {
+				continue
+			}
+
+			mig := m.migrations[i]
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted, it has no down file", mig.Version, mig.Name)
+			}
+
+			m.Logger.WithFields(logrus.Fields{"version": mig.Version, "name": mig.Name}).Info("reverting migration")
+			if err = m.apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, mig)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// Status returns every known migration and whether it has been applied, followed by the versions
+// applied to the database that this binary does not know, marked Unknown. It only reads, a database
+// never migrated has no version tracking table and every migration is pending.
+func (m *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var tables int
+	err = conn.QueryRowContext(ctx, m.dialect.tableExists()).Scan(&tables)
+	if err != nil {
+		return
+	}
+
+	if tables == 0 {
+		statuses = make([]Status, 0, len(m.migrations))
+		for _, mig := range m.migrations {
+			statuses = append(statuses, Status{Version: mig.Version, Name: mig.Name})
+		}
+		return statuses, nil
+	}
+
+	statuses, unknown, err := m.status(ctx, conn)
+	return append(statuses, unknown...), err
+}
+
+// Force records the database as being cleanly migrated up to version, without running anything.
+// It is the way out of a dirty state once the failed migration has been fixed by hand.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		table, ph := m.dialect.table(), m.dialect.placeholder
+
+		_, err := conn.ExecContext(ctx, `DELETE FROM `+table+` WHERE version > `+ph(1), version)
+		if err != nil {
+			return err
+		}
+
+		_, err = conn.ExecContext(ctx, `UPDATE `+table+` SET dirty = `+ph(1)+` WHERE version <= `+ph(2), false, version)
+		if err != nil {
+			return err
+		}
+
+		statuses, err := m.knownStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			if s.Applied || s.Version > version {
+				continue
+			}
+
+			_, err = conn.ExecContext(ctx, `INSERT INTO `+table+` (version, name, dirty, applied_at) VALUES (`+ph(1)+`, `+ph(2)+`, `+ph(3)+`, `+ph(4)+`)`,
+				s.Version, s.Name, false, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// locked runs fn on a dedicated connection holding the advisory lock, so that only one
+// replica migrates at a time
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	err = m.dialect.lock(ctx, conn)
+	if err != nil {
+		return
+	}
+	defer func() {
+		errUnlock := m.dialect.unlock(ctx, conn)
+		if errUnlock != nil {
+			m.Logger.WithError(errUnlock).Error("releasing the migration lock")
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, m.dialect.createTable())
+	if err != nil {
+		return
+	}
+
+	return fn(conn)
+}
+
+// status merges the known migrations with the rows of schema_migrations
+// knownStatus returns the status of every known migration, in the order of m.migrations. It fails
+// when the database has a version this binary does not know, which it could not migrate safely.
+func (m *Migrator) knownStatus(ctx context.Context, conn *sql.Conn) ([]Status, error) {
+	statuses, unknown, err := m.status(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("database is at version %d which is unknown to this binary", unknown[0].Version)
+	}
+
+	return statuses, nil
+}
+
+// status returns the status of every known migration, in the order of m.migrations, and apart
+// the versions applied to the database that no known migration has, in version order
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) (statuses []Status, unknown []Status, err error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, dirty, applied_at FROM `+m.dialect.table()+` ORDER BY version`)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			m.Logger.WithError(errRow).Error("closing rows")
+		}
+	}()
+
+	applied := map[int64]Status{}
+	for rows.Next() {
+		s := Status{Applied: true}
+		err = rows.Scan(&s.Version, &s.Dirty, &s.AppliedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		applied[s.Version] = s
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	statuses = make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s, ok := applied[mig.Version]
+		if !ok {
+			s = Status{Version: mig.Version}
+		}
+		s.Name = mig.Name
+		statuses = append(statuses, s)
+		delete(applied, mig.Version)
+	}
+
+	for _, s := range applied {
+		s.Unknown = true
+		unknown = append(unknown, s)
+	}
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].Version < unknown[j].Version
+	})
+
+	return statuses, unknown, nil
+}
+
+func checkClean(statuses []Status) error {
+	for _, s := range statuses {
+		if s.Dirty {
+			return fmt.Errorf("database is dirty at version %d, fix it by hand and force the version", s.Version)
+		}
+	}
+
+	return nil
+}
+
+// apply runs one migration in the given direction. The version is marked dirty while its
+// statements run, so that a failure on a database without transactional DDL is noticed.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) (err error) {
+	table, ph := m.dialect.table(), m.dialect.placeholder
+
+	script := mig.Down
+	if up {
+		script = mig.Up
+		_, err = conn.ExecContext(ctx, `INSERT INTO `+table+` (version, name, dirty, applied_at) VALUES (`+ph(1)+`, `+ph(2)+`, `+ph(3)+`, `+ph(4)+`)`,
+			mig.Version, mig.Name, true, time.Now())
+	} else {
+		_, err = conn.ExecContext(ctx, `UPDATE `+table+` SET dirty = `+ph(1)+` WHERE version = `+ph(2), true, mig.Version)
+	}
+	if err != nil {
+		return
+	}
+
+	err = m.run(ctx, conn, script)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+	}
+
+	if up {
+		_, err = conn.ExecContext(ctx, `UPDATE `+table+` SET dirty = `+ph(1)+` WHERE version = `+ph(2), false, mig.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, `DELETE FROM `+table+` WHERE version = `+ph(1), mig.Version)
+	}
+
+	return
+}
+
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script string) (err error) {
+	statements := splitStatements(script)
+
+	if !m.dialect.transactional() {
+		for _, statement := range statements {
+			if _, err = conn.ExecContext(ctx, statement); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	for _, statement := range statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			errRollback := tx.Rollback()
+			if errRollback != nil {
+				m.Logger.WithError(errRollback).Error("rolling back migration")
+			}
+			return
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,325 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/migrations"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/migration"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+	_ "modernc.org/sqlite"
+)
+
+var source = fstest.MapFS{
+	"1_create_post.up.sql":   {Data: []byte("CREATE TABLE post (title text DEFAULT 'a;b');\nCREATE INDEX post_title ON post (title);")},
+	"1_create_post.down.sql": {Data: []byte("DROP TABLE post;")},
+	"2_post_trigger.up.sql": {Data: []byte(`-- keep the updated_at column in sync;
+CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); RETURN NEW; END $$ LANGUAGE plpgsql;`)},
+	"2_post_trigger.down.sql": {Data: []byte("DROP FUNCTION touch();")},
+	"README.md":               {Data: []byte("not a migration")},
+}
+
+func expectLocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS public.schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func versionRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"version", "dirty", "applied_at"})
+}
+
+func TestUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLocked(mock)
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM public.schema_migrations").
+		WillReturnRows(versionRows().AddRow(1, false, time.Now()))
+	mock.ExpectExec("INSERT INTO public.schema_migrations").
+		WithArgs(2, "post_trigger", true, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE FUNCTION touch\(\) RETURNS trigger AS \$\$ BEGIN NEW.updated_at = now\(\); RETURN NEW; END \$\$ LANGUAGE plpgsql`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE public.schema_migrations SET dirty").
+		WithArgs(false, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlocked(mock)
+
+	m, err := migration.New(db, "postgres", source, logrus.New())
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.TODO())
+	assert.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, int64(2), applied[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpSplitStatements(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLocked(mock)
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM public.schema_migrations").
+		WillReturnRows(versionRows().AddRow(2, false, time.Now()))
+	mock.ExpectExec("INSERT INTO public.schema_migrations").
+		WithArgs(1, "create_post", true, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE post \(title text DEFAULT 'a;b'\)$`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX post_title ON post \(title\)$`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE public.schema_migrations SET dirty").
+		WithArgs(false, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlocked(mock)
+
+	m, err := migration.New(db, "postgres", source, logrus.New())
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLocked(mock)
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM public.schema_migrations").
+		WillReturnRows(versionRows().AddRow(1, false, time.Now()))
+	mock.ExpectExec("INSERT INTO public.schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE FUNCTION touch").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	expectUnlocked(mock)
+
+	m, err := migration.New(db, "postgres", source, logrus.New())
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.TODO())
+	assert.Error(t, err)
+	assert.Len(t, applied, 0)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpDirty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLocked(mock)
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM public.schema_migrations").
+		WillReturnRows(versionRows().AddRow(1, true, time.Now()))
+	expectUnlocked(mock)
+
+	m, err := migration.New(db, "postgres", source, logrus.New())
+	require.NoError(t, err)
+
+	_, err = m.Up(context.TODO())
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM schema_migrations").
+		WillReturnRows(versionRows().AddRow(1, false, time.Now()).AddRow(2, false, time.Now()))
+	mock.ExpectExec("UPDATE schema_migrations SET dirty").
+		WithArgs(true, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DROP FUNCTION touch\(\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version").
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT RELEASE_LOCK\(\?\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	m, err := migration.New(db, "mysql", source, logrus.New())
+	require.NoError(t, err)
+
+	reverted, err := m.Down(context.TODO(), 1)
+	assert.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, int64(2), reverted[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM information_schema.tables").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM public.schema_migrations").
+		WillReturnRows(versionRows().AddRow(1, false, time.Now()))
+
+	m, err := migration.New(db, "postgres", source, logrus.New())
+	require.NoError(t, err)
+
+	statuses, err := m.Status(context.TODO())
+	assert.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.Equal(t, "create_post", statuses[0].Name)
+	assert.False(t, statuses[1].Applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusNeverMigrated(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "post.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migration.New(db, "sqlite", source, logrus.New())
+	require.NoError(t, err)
+
+	statuses, err := m.Status(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	for _, s := range statuses {
+		assert.False(t, s.Applied, s.Name)
+	}
+
+	// the status is read only, the version tracking table is left to Up
+	var tables int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables))
+	assert.Zero(t, tables)
+}
+
+func TestForce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLocked(mock)
+	mock.ExpectExec("DELETE FROM public.schema_migrations WHERE version >").
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE public.schema_migrations SET dirty").
+		WithArgs(false, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM public.schema_migrations").
+		WillReturnRows(versionRows())
+	mock.ExpectExec("INSERT INTO public.schema_migrations").
+		WithArgs(1, "create_post", false, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlocked(mock)
+
+	m, err := migration.New(db, "postgres", source, logrus.New())
+	require.NoError(t, err)
+
+	err = m.Force(context.TODO(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewUnknownKind(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	_, err = migration.New(db, "oracle", source, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	for _, kind := range []string{"postgres", "mysql", "sqlite"} {
+		src, err := migrations.Source(kind)
+		require.NoError(t, err)
+
+		_, err = migration.New(db, kind, src, logrus.New())
+		assert.NoError(t, err, kind)
+	}
+}
+
+func TestSqlite(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "post.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	src, err := migrations.Source("sqlite")
+	require.NoError(t, err)
+
+	m, err := migration.New(db, "sqlite", src, logrus.New())
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.TODO())
+	require.NoError(t, err)
+	assert.NotEmpty(t, applied)
+
+	statuses, err := m.Status(context.TODO())
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, s.Name)
+		assert.False(t, s.Dirty, s.Name)
+	}
+
+	// the trigger bodies survived the statement splitting
+	_, err = db.Exec(`INSERT INTO audit_event (actor, action, entity, entity_id, created_at) VALUES ('cli', 'create', 'post', 1, '2020-10-01 00:00:00')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM audit_event`)
+	assert.Error(t, err)
+
+	reverted, err := m.Down(context.TODO(), len(applied))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(applied))
+}
+
+func TestStatusAheadOfBinary(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "post.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	src, err := migrations.Source("sqlite")
+	require.NoError(t, err)
+
+	m, err := migration.New(db, "sqlite", src, logrus.New())
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.TODO())
+	require.NoError(t, err)
+
+	// a newer release applied a migration this binary does not have
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (99990101000000, 'future', 0, '2020-10-01 00:00:00')`)
+	require.NoError(t, err)
+
+	statuses, err := m.Status(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, statuses, len(applied)+1)
+	last := statuses[len(statuses)-1]
+	assert.Equal(t, int64(99990101000000), last.Version)
+	assert.True(t, last.Applied)
+	assert.True(t, last.Unknown)
+
+	_, err = m.Up(context.TODO())
+	assert.EqualError(t, err, "database is at version 99990101000000 which is unknown to this binary")
+}
@@ -0,0 +1,72 @@
+package migration
+
+import "strings"
+
+// splitStatements splits a migration file into single statements, since not every driver
+// accepts several statements in one Exec. Semicolons inside quotes, dollar quoted bodies,
+// comments and the BEGIN ... END body of a sqlite trigger do not end a statement.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+
+		switch {
+		case ch == '-' && strings.HasPrefix(script[i:], "--"):
+			// skip the line comment
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+				continue
+			}
+			i += end
+			current.WriteByte('\n')
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := closing(script, i+1, string(ch))
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == '$' && strings.HasPrefix(script[i:], "$$"):
+			end := closing(script, i+2, "$$")
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == ';' && inTriggerBody(current.String()):
+			current.WriteByte(ch)
+		case ch == ';':
+			flush()
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// closing returns the index right after the delimiter closing the quote opened before from
+func closing(script string, from int, delimiter string) int {
+	end := strings.Index(script[from:], delimiter)
+	if end < 0 {
+		return len(script)
+	}
+
+	return from + end + len(delimiter)
+}
+
+// inTriggerBody reports whether statement is a CREATE TRIGGER whose BEGIN ... END body is still open
+func inTriggerBody(statement string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(statement))
+	if !strings.HasPrefix(upper, "CREATE TRIGGER") || !strings.Contains(upper, "BEGIN") {
+		return false
+	}
+
+	return !strings.HasSuffix(upper, "END")
+}
@@ -0,0 +1,363 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/lib/pq"
+)
+
+// Dialect represent what sets the sql engines apart for the repositories. The queries are
+// written once with ? placeholders and unqualified table names, the dialect does the rest.
+type Dialect interface {
+	// Name is the database kind the dialect speaks, as in database.kind
+	Name() string
+	// Placeholder is the bind parameter of the n-th argument, counting from 1
+	Placeholder(n int) string
+	// Quote quotes an identifier
+	Quote(ident string) string
+	// Table is the quoted, schema qualified name of a table
+	Table(name string) string
+	// Returning reports whether INSERT ... RETURNING hands the generated id back,
+	// the id is read from LastInsertId otherwise
+	Returning() bool
+	// Upsert is the clause turning an INSERT colliding on the conflict columns into an update of columns
+	Upsert(conflict []string, columns []string) string
+	// Precision is the resolution of the timestamp columns
+	Precision() time.Duration
+	// Time is the value bound for a timestamp column
+	Time(t time.Time) interface{}
+	// TranslateError maps the driver errors the usecases care about to domain errors
+	TranslateError(err error) error
+	// SerializationFailure reports whether err aborted a transaction that succeeds when run again
+	SerializationFailure(err error) bool
+	// Transient reports whether err is likely to go away when the statement runs again, such as
+	// a deadlock, a lock timeout or a lost connection
+	Transient(err error) bool
+}
+
+// DialectFor returns the dialect of the given database kind
+func DialectFor(kind string) (Dialect, error) {
+	switch kind {
+	case "postgres":
+		return postgresDialect{}, nil
+	case "mysql":
+		return mysqlDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	default:
+		return nil, fmt.Errorf("no sql dialect for database kind %q", kind)
+	}
+}
+
+// Rebind replaces the ? placeholders of query with those of the dialect
+func Rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Insert runs the INSERT query through stmts and returns the id generated for the new row
+func Insert(ctx context.Context, stmts *StmtCache, d Dialect, query string, args ...interface{}) (id int64, err error) {
+	if d.Returning() {
+		statement, err := stmts.Prepare(ctx, Rebind(d, query+" RETURNING id"))
+		if err != nil {
+			return 0, err
+		}
+
+		err = statement.QueryRowContext(ctx, args...).Scan(&id)
+		return id, d.TranslateError(err)
+	}
+
+	statement, err := stmts.Prepare(ctx, Rebind(d, query))
+	if err != nil {
+		return
+	}
+
+	res, err := statement.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, d.TranslateError(err)
+	}
+
+	return res.LastInsertId()
+}
+
+func quoteAll(d Dialect, idents []string) []string {
+	quoted := make([]string, len(idents))
+	for i, ident := range idents {
+		quoted[i] = d.Quote(ident)
+	}
+
+	return quoted
+}
+
+type postgresDialect struct{}
+
+const (
+	// uniqueViolation is the SQLSTATE postgres reports when a unique index rejects a row
+	uniqueViolation = "23505"
+	// serializationFailure and deadlockDetected are the SQLSTATEs of the transactions
+	// postgres aborts to keep concurrent transactions serializable
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func (d postgresDialect) Table(name string) string {
+	return "public." + d.Quote(name)
+}
+
+func (postgresDialect) Returning() bool {
+	return true
+}
+
+func (d postgresDialect) Upsert(conflict []string, columns []string) string {
+	return excludedUpsert(d, conflict, columns)
+}
+
+func (postgresDialect) Precision() time.Duration {
+	return time.Second
+}
+
+func (postgresDialect) Time(t time.Time) interface{} {
+	return t
+}
+
+func (postgresDialect) TranslateError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return domain.ErrConflict
+	}
+
+	return err
+}
+
+func (postgresDialect) SerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+	}
+
+	return false
+}
+
+// Transient accepts the SQLSTATE classes of the aborted transactions, the connection
+// exceptions and the exhausted resources, and the server shutting down or starting up
+func (postgresDialect) Transient(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "40", "08", "53":
+			return true
+		}
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+		return false
+	}
+
+	return connectionError(err)
+}
+
+type mysqlDialect struct{}
+
+// errDuplicateEntry is the error number mysql reports when a unique index rejects a row
+const errDuplicateEntry = 1062
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+}
+
+func (d mysqlDialect) Table(name string) string {
+	return d.Quote(name)
+}
+
+func (mysqlDialect) Returning() bool {
+	return false
+}
+
+// Upsert ignores the conflict columns, mysql updates on a collision with any unique index
+func (d mysqlDialect) Upsert(conflict []string, columns []string) string {
+	set := make([]string, len(columns))
+	for i, column := range quoteAll(d, columns) {
+		set[i] = column + " = VALUES(" + column + ")"
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+}
+
+func (mysqlDialect) Precision() time.Duration {
+	return time.Second
+}
+
+func (mysqlDialect) Time(t time.Time) interface{} {
+	return t
+}
+
+func (mysqlDialect) TranslateError(err error) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == errDuplicateEntry {
+		return domain.ErrConflict
+	}
+
+	return err
+}
+
+// SerializationFailure is always false, only the postgres transactions are run again
+func (mysqlDialect) SerializationFailure(err error) bool {
+	return false
+}
+
+// mysql error numbers of the failures that go away when the statement runs again
+const (
+	errTooManyConnections = 1040
+	errLockWaitTimeout    = 1205
+	errLockDeadlock       = 1213
+)
+
+func (mysqlDialect) Transient(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case errTooManyConnections, errLockWaitTimeout, errLockDeadlock:
+			return true
+		}
+		return false
+	}
+
+	return errors.Is(err, mysql.ErrInvalidConn) || connectionError(err)
+}
+
+type sqliteDialect struct{}
+
+// extended result codes sqlite reports when a unique index or the primary key rejects a row
+const (
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqliteDialect) Quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func (d sqliteDialect) Table(name string) string {
+	return d.Quote(name)
+}
+
+func (sqliteDialect) Returning() bool {
+	return true
+}
+
+func (d sqliteDialect) Upsert(conflict []string, columns []string) string {
+	return excludedUpsert(d, conflict, columns)
+}
+
+func (sqliteDialect) Precision() time.Duration {
+	return time.Millisecond
+}
+
+func (sqliteDialect) Time(t time.Time) interface{} {
+	return SQLiteTime(t)
+}
+
+// TranslateError matches the code of the driver error without importing the driver
+func (sqliteDialect) TranslateError(err error) error {
+	if coded, ok := err.(interface{ Code() int }); ok {
+		switch coded.Code() {
+		case sqliteConstraintPrimaryKey, sqliteConstraintUnique:
+			return domain.ErrConflict
+		}
+	}
+
+	return err
+}
+
+// SerializationFailure is always false, sqlite runs one write transaction at a time
+func (sqliteDialect) SerializationFailure(err error) bool {
+	return false
+}
+
+// primary result codes of sqlite for a database or a table another connection holds
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// Transient accepts the busy and locked results, the low byte of an extended code is its primary code
+func (sqliteDialect) Transient(err error) bool {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		code := coded.Code() & 0xff
+		return code == sqliteBusy || code == sqliteLocked
+	}
+
+	return connectionError(err)
+}
+
+// connectionError reports whether err is a connection the driver gave up on or a network failure.
+// The deadline and the cancellation of the context are final, even though they look like timeouts.
+func connectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
+
+// excludedUpsert is the ON CONFLICT clause shared by postgres and sqlite
+func excludedUpsert(d Dialect, conflict []string, columns []string) string {
+	set := make([]string, len(columns))
+	for i, column := range quoteAll(d, columns) {
+		set[i] = column + " = EXCLUDED." + column
+	}
+
+	return "ON CONFLICT (" + strings.Join(quoteAll(d, conflict), ", ") + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
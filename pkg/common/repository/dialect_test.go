@@ -0,0 +1,137 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM post WHERE title = ? AND id > ? LIMIT ?"
+
+	postgres, err := repository.DialectFor("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM post WHERE title = $1 AND id > $2 LIMIT $3", repository.Rebind(postgres, query))
+
+	mysqlDialect, err := repository.DialectFor("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, query, repository.Rebind(mysqlDialect, query))
+
+	sqlite, err := repository.DialectFor("sqlite")
+	require.NoError(t, err)
+	at := time.Date(2020, 10, 1, 7, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+	assert.Equal(t, "2020-10-01 00:00:00.000", sqlite.Time(at))
+
+	_, err = repository.DialectFor("oracle")
+	assert.Error(t, err)
+}
+
+func TestDialects(t *testing.T) {
+	cases := []struct {
+		kind       string
+		table      string
+		upsert     string
+		duplicates []error
+	}{
+		{
+			kind:       "postgres",
+			table:      `public."post"`,
+			upsert:     `ON CONFLICT ("title") DO UPDATE SET "content" = EXCLUDED."content", "updated_at" = EXCLUDED."updated_at"`,
+			duplicates: []error{&pq.Error{Code: "23505"}},
+		},
+		{
+			kind:       "mysql",
+			table:      "`post`",
+			upsert:     "ON DUPLICATE KEY UPDATE `content` = VALUES(`content`), `updated_at` = VALUES(`updated_at`)",
+			duplicates: []error{&mysql.MySQLError{Number: 1062}},
+		},
+		{
+			kind:       "sqlite",
+			table:      `"post"`,
+			upsert:     `ON CONFLICT ("title") DO UPDATE SET "content" = EXCLUDED."content", "updated_at" = EXCLUDED."updated_at"`,
+			duplicates: []error{codedError(2067), codedError(1555)},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.kind, func(t *testing.T) {
+			d, err := repository.DialectFor(c.kind)
+			require.NoError(t, err)
+
+			assert.Equal(t, c.kind, d.Name())
+			assert.Equal(t, c.table, d.Table("post"))
+			assert.Equal(t, c.upsert, d.Upsert([]string{"title"}, []string{"content", "updated_at"}))
+			for _, duplicate := range c.duplicates {
+				assert.Equal(t, domain.ErrConflict, d.TranslateError(duplicate))
+			}
+
+			other := errors.New("connection refused")
+			assert.Equal(t, other, d.TranslateError(other))
+			assert.Nil(t, d.TranslateError(nil))
+		})
+	}
+}
+
+func TestTransient(t *testing.T) {
+	cases := []struct {
+		kind      string
+		transient []error
+		final     []error
+	}{
+		{
+			kind:      "postgres",
+			transient: []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"}, &pq.Error{Code: "08006"}, &pq.Error{Code: "53300"}, &pq.Error{Code: "57P01"}},
+			final:     []error{&pq.Error{Code: "23505"}, &pq.Error{Code: "42P01"}, &pq.Error{Code: "57014"}},
+		},
+		{
+			kind:      "mysql",
+			transient: []error{&mysql.MySQLError{Number: 1213}, &mysql.MySQLError{Number: 1205}, &mysql.MySQLError{Number: 1040}, mysql.ErrInvalidConn},
+			final:     []error{&mysql.MySQLError{Number: 1062}, &mysql.MySQLError{Number: 1146}},
+		},
+		{
+			kind:      "sqlite",
+			transient: []error{codedError(5), codedError(517), codedError(6)},
+			final:     []error{codedError(2067), codedError(1)},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.kind, func(t *testing.T) {
+			d, err := repository.DialectFor(c.kind)
+			require.NoError(t, err)
+
+			transient := append(c.transient, driver.ErrBadConn, fmt.Errorf("fetching: %w", driver.ErrBadConn), &net.OpError{Op: "read", Err: errors.New("connection reset by peer")})
+			for _, err := range transient {
+				assert.True(t, d.Transient(err), "%v is transient", err)
+			}
+
+			final := append(c.final, nil, errors.New("Unexpected Error"), context.DeadlineExceeded, context.Canceled, sql.ErrNoRows)
+			for _, err := range final {
+				assert.False(t, d.Transient(err), "%v is final", err)
+			}
+		})
+	}
+}
+
+// codedError mimics the error of the sqlite driver, which reports the extended result code
+type codedError int
+
+func (e codedError) Error() string {
+	return "constraint failed"
+}
+
+func (e codedError) Code() int {
+	return int(e)
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"encoding/base64"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -9,22 +12,53 @@ const (
 	timeFormat = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
 )
 
-// DecodeCursor will decode cursor from user for mysql
-func DecodeCursor(encodedTime string) (time.Time, error) {
-	byt, err := base64.StdEncoding.DecodeString(encodedTime)
+// DecodeCursor will decode a cursor from user made of a creation time and the id breaking the ties
+// of that time. A cursor holding only the time, as issued before the id was added, decodes with
+// the largest id so that it still goes past every row of its time.
+func DecodeCursor(encoded string) (t time.Time, id int64, err error) {
+	byt, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, 0, err
 	}
 
-	timeString := string(byt)
-	t, err := time.Parse(timeFormat, timeString)
+	timeString, idString := string(byt), ""
+	if i := strings.LastIndexByte(timeString, ','); i >= 0 {
+		timeString, idString = timeString[:i], timeString[i+1:]
+	}
+
+	t, err = time.Parse(timeFormat, timeString)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
 
-	return t, err
+	if idString == "" {
+		return t, math.MaxInt64, nil
+	}
+
+	id, err = strconv.ParseInt(idString, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return
+}
+
+// EncodeCursor will encode the creation time and the id of the last row of a page to user
+func EncodeCursor(t time.Time, id int64) string {
+	return base64.StdEncoding.EncodeToString([]byte(t.Format(timeFormat) + "," + strconv.FormatInt(id, 10)))
 }
 
-// EncodeCursor will encode cursor from mysql to user
-func EncodeCursor(t time.Time) string {
-	timeString := t.Format(timeFormat)
+// DecodeIDCursor will decode an id based cursor from user
+func DecodeIDCursor(encodedID string) (int64, error) {
+	byt, err := base64.StdEncoding.DecodeString(encodedID)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(string(byt), 10, 64)
+}
 
-	return base64.StdEncoding.EncodeToString([]byte(timeString))
+// EncodeIDCursor will encode an id based cursor to user
+func EncodeIDCursor(id int64) string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
 }
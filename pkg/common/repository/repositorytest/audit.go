@@ -0,0 +1,74 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AuditRepositoryFactory returns an empty repository, it is called once for every test of the suite
+type AuditRepositoryFactory func(t *testing.T) domain.AuditRepository
+
+// RunAuditRepositorySuite checks the semantics of a domain.AuditRepository
+func RunAuditRepositorySuite(t *testing.T, factory AuditRepositoryFactory) {
+	t.Run("StoreAndFetch", func(t *testing.T) {
+		repo := factory(t)
+
+		for _, e := range []struct {
+			action   string
+			entityID int64
+		}{{"create", 1}, {"create", 2}, {"update", 1}, {"delete", 1}} {
+			event := domain.AuditEvent{
+				Actor:     "cli",
+				Action:    e.action,
+				Entity:    domain.AuditEntityPost,
+				EntityID:  e.entityID,
+				After:     json.RawMessage(`{"title":"title"}`),
+				RequestID: "req-1",
+				IP:        "10.0.0.1",
+				CreatedAt: time.Now(),
+			}
+			require.NoError(t, repo.Store(context.TODO(), &event))
+			assert.NotZero(t, event.ID)
+		}
+
+		// the events of the entity in the order they were stored, a full page comes with a cursor
+		list, cursor, err := repo.Fetch(context.TODO(), domain.AuditEntityPost, 1, "", 2)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, "create", list[0].Action)
+		assert.Equal(t, "update", list[1].Action)
+		assert.Equal(t, "cli", list[0].Actor)
+		assert.Equal(t, "req-1", list[0].RequestID)
+		assert.Empty(t, list[0].Before)
+		assert.JSONEq(t, `{"title":"title"}`, string(list[0].After))
+		require.NotEmpty(t, cursor)
+
+		list, cursor, err = repo.Fetch(context.TODO(), domain.AuditEntityPost, 1, cursor, 2)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "delete", list[0].Action)
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("FetchUnknownEntity", func(t *testing.T) {
+		repo := factory(t)
+
+		list, cursor, err := repo.Fetch(context.TODO(), domain.AuditEntityPost, 1000, "", 10)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("FetchInvalidCursor", func(t *testing.T) {
+		repo := factory(t)
+
+		_, _, err := repo.Fetch(context.TODO(), domain.AuditEntityPost, 1, "not a cursor", 10)
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+}
@@ -0,0 +1,43 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AuthorRepositoryFactory returns an empty repository, it is called once for every test of the suite
+type AuthorRepositoryFactory func(t *testing.T) domain.AuthorRepository
+
+// RunAuthorRepositorySuite checks the semantics of a domain.AuthorRepository, resolution is that of the times it stores
+func RunAuthorRepositorySuite(t *testing.T, factory AuthorRepositoryFactory, resolution time.Duration) {
+	t.Run("StoreAndGetByID", func(t *testing.T) {
+		repo := factory(t)
+
+		now := time.Now().UTC().Truncate(resolution)
+		first := domain.Author{Name: "Iman Tumorang", CreatedAt: now, UpdatedAt: now}
+		second := domain.Author{Name: "Dummy User", CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, repo.Store(context.TODO(), &first))
+		require.NoError(t, repo.Store(context.TODO(), &second))
+		assert.NotZero(t, first.ID)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		got, err := repo.GetByID(context.TODO(), first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, got.ID)
+		assert.Equal(t, "Iman Tumorang", got.Name)
+		assert.True(t, now.Equal(got.CreatedAt))
+		assert.True(t, now.Equal(got.UpdatedAt))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := factory(t)
+
+		_, err := repo.GetByID(context.TODO(), 1000)
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+}
@@ -0,0 +1,79 @@
+package repositorytest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/migrations"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/migration"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// DSNVariables name the environment variables giving the databases the sql suites run against
+var DSNVariables = map[string]string{
+	"postgres": "POSTGRES_TEST_DSN",
+	"mysql":    "MYSQL_TEST_DSN",
+}
+
+// OpenDatabase returns an empty database of the given kind, migrated with the embedded migrations.
+// sqlite gets a new file for every call. postgres and mysql use the database named by DSNVariables,
+// the test is skipped when it is unset. Their tables are truncated, so never point the variables at
+// a database holding data to keep.
+func OpenDatabase(t testing.TB, kind string) (*sql.DB, repository.Dialect) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "post.db")
+	if kind != "sqlite" {
+		dsn = os.Getenv(DSNVariables[kind])
+		if dsn == "" {
+			t.Skipf("%s is not set", DSNVariables[kind])
+		}
+	}
+	if kind == "mysql" {
+		// the rows affected are counted as the service counts them, see database.Open
+		c, err := mysql.ParseDSN(dsn)
+		require.NoError(t, err)
+		c.ClientFoundRows = true
+		dsn = c.FormatDSN()
+	}
+
+	db, err := sql.Open(kind, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	if kind == "sqlite" {
+		db.SetMaxOpenConns(1)
+	}
+
+	src, err := migrations.Source(kind)
+	require.NoError(t, err)
+	m, err := migration.New(db, kind, src, logrus.New())
+	require.NoError(t, err)
+	_, err = m.Up(context.TODO())
+	require.NoError(t, err)
+
+	dialect, err := repository.DialectFor(kind)
+	require.NoError(t, err)
+
+	// TRUNCATE goes past the rules and triggers keeping audit_event append-only
+	switch kind {
+	case "postgres":
+		_, err = db.Exec(`TRUNCATE public.post, public.author, public.audit_event, public.rate_limit_counter, public.idempotency_key RESTART IDENTITY`)
+		require.NoError(t, err)
+	case "mysql":
+		for _, table := range []string{"post", "author", "audit_event", "rate_limit_counter", "idempotency_key"} {
+			_, err = db.Exec("TRUNCATE " + dialect.Table(table))
+			require.NoError(t, err)
+		}
+	}
+
+	return db, dialect
+}
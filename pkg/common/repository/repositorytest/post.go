@@ -0,0 +1,267 @@
+// Package repositorytest holds the contract every implementation of the domain repositories
+// must meet, whatever the backend. Each backend runs the suites from its own tests.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PostRepositoryFactory returns an empty repository, it is called once for every test of the suite
+type PostRepositoryFactory func(t *testing.T) domain.PostRepository
+
+// RunPostRepositorySuite checks the semantics of a domain.PostRepository
+func RunPostRepositorySuite(t *testing.T, factory PostRepositoryFactory) {
+	s := postSuite{factory: factory}
+
+	t.Run("StoreAndGet", s.storeAndGet)
+	t.Run("StoreKeepsTimestamps", s.storeKeepsTimestamps)
+	t.Run("StoreConflict", s.storeConflict)
+	t.Run("NotFound", s.notFound)
+	t.Run("FetchEmpty", s.fetchEmpty)
+	t.Run("FetchPages", s.fetchPages)
+	t.Run("FetchTies", s.fetchTies)
+	t.Run("FetchExactPage", s.fetchExactPage)
+	t.Run("FetchInvalidCursor", s.fetchInvalidCursor)
+	t.Run("Update", s.update)
+	t.Run("UpdateConflict", s.updateConflict)
+	t.Run("Delete", s.delete)
+}
+
+type postSuite struct {
+	factory PostRepositoryFactory
+}
+
+// store stores posts with the given titles, the repository sets their timestamps
+func (s postSuite) store(t *testing.T, repo domain.PostRepository, titles ...string) []domain.Post {
+	return s.storeAt(t, repo, time.Time{}, 0, titles...)
+}
+
+// storeAt stores posts with the given titles, the i-th created at createdAt plus i steps.
+// A zero createdAt leaves the timestamps to the repository.
+func (s postSuite) storeAt(t *testing.T, repo domain.PostRepository, createdAt time.Time, step time.Duration, titles ...string) []domain.Post {
+	posts := make([]domain.Post, 0, len(titles))
+	for i, title := range titles {
+		p := domain.Post{Title: title, Content: "content of " + title, Author: domain.Author{ID: 1}}
+		if !createdAt.IsZero() {
+			p.CreatedAt = createdAt.Add(time.Duration(i) * step)
+			p.UpdatedAt = p.CreatedAt
+		}
+		require.NoError(t, repo.Store(context.TODO(), &p))
+		posts = append(posts, p)
+	}
+
+	return posts
+}
+
+// fetchAll reads every page of num posts and returns the titles in the order read
+func fetchAll(t *testing.T, repo domain.PostRepository, num int64, maxPages int) []string {
+	var titles []string
+	cursor := ""
+	for pages := 1; ; pages++ {
+		require.LessOrEqual(t, pages, maxPages, "the pages never end")
+
+		list, next, err := repo.Fetch(context.TODO(), cursor, num)
+		require.NoError(t, err)
+		require.LessOrEqual(t, int64(len(list)), num)
+
+		for _, p := range list {
+			titles = append(titles, p.Title)
+		}
+		if next == "" {
+			return titles
+		}
+		cursor = next
+	}
+}
+
+func (s postSuite) storeAndGet(t *testing.T) {
+	repo := s.factory(t)
+	stored := s.store(t, repo, "first", "second")
+
+	assert.NotZero(t, stored[0].ID)
+	assert.NotEqual(t, stored[0].ID, stored[1].ID)
+	assert.False(t, stored[0].CreatedAt.IsZero())
+	assert.False(t, stored[0].UpdatedAt.IsZero())
+
+	byID, err := repo.GetByID(context.TODO(), stored[0].ID)
+	require.NoError(t, err)
+	assertSamePost(t, stored[0], byID)
+
+	byTitle, err := repo.GetByTitle(context.TODO(), "second")
+	require.NoError(t, err)
+	assertSamePost(t, stored[1], byTitle)
+}
+
+func (s postSuite) storeKeepsTimestamps(t *testing.T) {
+	repo := s.factory(t)
+
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	p := domain.Post{Title: "imported", Content: "content", Author: domain.Author{ID: 1}, CreatedAt: createdAt, UpdatedAt: createdAt.Add(time.Hour)}
+	require.NoError(t, repo.Store(context.TODO(), &p))
+
+	got, err := repo.GetByID(context.TODO(), p.ID)
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(got.CreatedAt), "the creation time is kept")
+	assert.True(t, createdAt.Add(time.Hour).Equal(got.UpdatedAt), "the update time is kept")
+}
+
+func (s postSuite) storeConflict(t *testing.T) {
+	repo := s.factory(t)
+	stored := s.store(t, repo, "title")
+
+	err := repo.Store(context.TODO(), &domain.Post{Title: "title", Content: "again", Author: domain.Author{ID: 1}})
+	assert.Equal(t, domain.ErrConflict, err)
+
+	// the first post is left as it was
+	got, err := repo.GetByTitle(context.TODO(), "title")
+	require.NoError(t, err)
+	assertSamePost(t, stored[0], got)
+}
+
+func (s postSuite) notFound(t *testing.T) {
+	repo := s.factory(t)
+	stored := s.store(t, repo, "title")
+
+	_, err := repo.GetByID(context.TODO(), stored[0].ID+1000)
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	_, err = repo.GetByTitle(context.TODO(), "unknown")
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func (s postSuite) fetchEmpty(t *testing.T) {
+	repo := s.factory(t)
+
+	list, cursor, err := repo.Fetch(context.TODO(), "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	assert.Empty(t, cursor)
+}
+
+func (s postSuite) fetchPages(t *testing.T) {
+	repo := s.factory(t)
+	// stored newest first, the pages list them oldest first
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.storeAt(t, repo, createdAt, -time.Second, "post 5", "post 4", "post 3", "post 2", "post 1")
+
+	// no post repeated nor skipped at the page boundaries
+	assert.Equal(t, []string{"post 1", "post 2", "post 3", "post 4", "post 5"}, fetchAll(t, repo, 2, 3))
+}
+
+func (s postSuite) fetchTies(t *testing.T) {
+	repo := s.factory(t)
+	// posts created within the same second, as a seed or an import does, end pages in the middle of
+	// their tie. The ids order them.
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.storeAt(t, repo, createdAt.Add(-time.Second), 0, "post 1")
+	s.storeAt(t, repo, createdAt, 0, "post 2", "post 3", "post 4", "post 5", "post 6")
+	s.storeAt(t, repo, createdAt.Add(time.Second), 0, "post 7")
+
+	assert.Equal(t, []string{"post 1", "post 2", "post 3", "post 4", "post 5", "post 6", "post 7"}, fetchAll(t, repo, 2, 4))
+}
+
+func (s postSuite) fetchExactPage(t *testing.T) {
+	repo := s.factory(t)
+	s.storeAt(t, repo, time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC), 0, "post 1", "post 2")
+
+	// a full page always comes with a cursor, the page after the last post is empty
+	list, cursor, err := repo.Fetch(context.TODO(), "", 2)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	require.NotEmpty(t, cursor)
+
+	list, cursor, err = repo.Fetch(context.TODO(), cursor, 2)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	assert.Empty(t, cursor)
+}
+
+func (s postSuite) fetchInvalidCursor(t *testing.T) {
+	repo := s.factory(t)
+
+	_, _, err := repo.Fetch(context.TODO(), "not a cursor", 10)
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func (s postSuite) update(t *testing.T) {
+	repo := s.factory(t)
+	stored := s.store(t, repo, "title")
+
+	p := stored[0]
+	p.Title = "changed title"
+	p.Content = "changed content"
+	p.Author = domain.Author{ID: 2}
+	p.UpdatedAt = p.CreatedAt.Add(time.Hour)
+	require.NoError(t, repo.Update(context.TODO(), &p))
+
+	got, err := repo.GetByID(context.TODO(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "changed title", got.Title)
+	assert.Equal(t, "changed content", got.Content)
+	assert.Equal(t, int64(2), got.Author.ID)
+	assert.True(t, stored[0].CreatedAt.Equal(got.CreatedAt), "the creation time is kept")
+	assert.True(t, p.UpdatedAt.Equal(got.UpdatedAt), "the update time is stored")
+
+	_, err = repo.GetByTitle(context.TODO(), "title")
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	// updating a post with its own values is no error
+	require.NoError(t, repo.Update(context.TODO(), &p))
+
+	missing := domain.Post{ID: p.ID + 1000, Title: "missing", UpdatedAt: p.UpdatedAt}
+	assert.True(t, errors.Is(repo.Update(context.TODO(), &missing), domain.ErrNotFound))
+}
+
+func (s postSuite) updateConflict(t *testing.T) {
+	repo := s.factory(t)
+	stored := s.store(t, repo, "first", "second")
+
+	p := stored[1]
+	p.Title = "first"
+	p.UpdatedAt = p.CreatedAt
+	assert.Equal(t, domain.ErrConflict, repo.Update(context.TODO(), &p))
+
+	// a post keeps its own title
+	p = stored[0]
+	p.Content = "changed"
+	p.UpdatedAt = p.CreatedAt
+	assert.NoError(t, repo.Update(context.TODO(), &p))
+}
+
+func (s postSuite) delete(t *testing.T) {
+	repo := s.factory(t)
+	stored := s.store(t, repo, "first", "second")
+
+	require.NoError(t, repo.Delete(context.TODO(), stored[0].ID))
+
+	_, err := repo.GetByID(context.TODO(), stored[0].ID)
+	assert.Equal(t, domain.ErrNotFound, err)
+	assert.True(t, errors.Is(repo.Delete(context.TODO(), stored[0].ID), domain.ErrNotFound))
+
+	// the title is free again
+	s.store(t, repo, "first")
+
+	list, _, err := repo.Fetch(context.TODO(), "", 10)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+// assertSamePost compares what a repository stores of a post, the author is kept as its id
+func assertSamePost(t *testing.T, expected, actual domain.Post) {
+	t.Helper()
+
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.Title, actual.Title)
+	assert.Equal(t, expected.Content, actual.Content)
+	assert.Equal(t, expected.Author.ID, actual.Author.ID)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), fmt.Sprintf("created at %s, read back %s", expected.CreatedAt, actual.CreatedAt))
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt), fmt.Sprintf("updated at %s, read back %s", expected.UpdatedAt, actual.UpdatedAt))
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// Router picks the database a query of the repositories runs on. Writes and transactions go to
+// the primary, reads go to the healthy replicas in turn. A client that wrote reads from the
+// primary for the read-your-writes window, so that it sees its write whatever the replication lag.
+type Router struct {
+	Primary  *sql.DB
+	Replicas []*sql.DB
+	// Window is how long the reads of a client go to the primary after it wrote
+	Window time.Duration
+	Logger logrus.FieldLogger
+
+	// healthy flags each replica with 1 while its last health check passed
+	healthy []int32
+	next    uint32
+
+	mu     sync.Mutex
+	writes map[string]time.Time
+}
+
+// NewRouter will create a router over the primary and its replicas. The replicas take reads
+// once a health check passed, see CheckReplicas.
+func NewRouter(primary *sql.DB, replicas []*sql.DB, window time.Duration, logger logrus.FieldLogger) *Router {
+	return &Router{
+		Primary:  primary,
+		Replicas: replicas,
+		Window:   window,
+		Logger:   logger,
+		healthy:  make([]int32, len(replicas)),
+		writes:   make(map[string]time.Time),
+	}
+}
+
+// Writer returns the primary and starts the read-your-writes window of the client of ctx
+func (r *Router) Writer(ctx context.Context) *sql.DB {
+	if key := session(ctx); key != "" && len(r.Replicas) > 0 {
+		r.mu.Lock()
+		r.writes[key] = time.Now().Add(r.Window)
+		r.mu.Unlock()
+	}
+
+	return r.Primary
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a copy of ctx whose reads go to the primary, for the reads that must see
+// every committed write whoever made it, such as those filling a shared cache
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Reader returns the next healthy replica, or the primary when there is none, the client of ctx
+// is within its read-your-writes window or ctx was given by WithPrimary
+func (r *Router) Reader(ctx context.Context) *sql.DB {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return r.Primary
+	}
+	if len(r.Replicas) == 0 || r.recentlyWrote(ctx) {
+		return r.Primary
+	}
+
+	start := atomic.AddUint32(&r.next, 1)
+	for i := range r.Replicas {
+		n := (int(start) + i) % len(r.Replicas)
+		if atomic.LoadInt32(&r.healthy[n]) == 1 {
+			return r.Replicas[n]
+		}
+	}
+
+	return r.Primary
+}
+
+func (r *Router) recentlyWrote(ctx context.Context) bool {
+	key := session(ctx)
+	if key == "" {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	until, ok := r.writes[key]
+	return ok && time.Now().Before(until)
+}
+
+// session identifies the client of the request carried by ctx, empty outside of a request
+func session(ctx context.Context) string {
+	info := domain.RequestInfoFromContext(ctx)
+	if info.Actor == "" && info.IP == "" {
+		return ""
+	}
+
+	return info.Actor + "@" + info.IP
+}
+
+// CheckReplicas pings every replica, a replica failing to answer within timeout takes no reads
+// until it answers again
+func (r *Router) CheckReplicas(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for i, replica := range r.Replicas {
+		wg.Add(1)
+		go func(i int, replica *sql.DB) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			err := replica.PingContext(ctx)
+			healthy := int32(1)
+			if err != nil {
+				healthy = 0
+			}
+
+			if atomic.SwapInt32(&r.healthy[i], healthy) != healthy {
+				logger := r.Logger.WithField("replica", i)
+				if err != nil {
+					logger.WithError(err).Warn("ejecting replica")
+				} else {
+					logger.Info("admitting replica")
+				}
+			}
+		}(i, replica)
+	}
+	wg.Wait()
+}
+
+// Watch checks the replicas every interval and forgets the expired read-your-writes windows,
+// until ctx is done
+func (r *Router) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.CheckReplicas(ctx, interval)
+			r.forget(now)
+		}
+	}
+}
+
+func (r *Router) forget(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, until := range r.writes {
+		if now.After(until) {
+			delete(r.writes, key)
+		}
+	}
+}
+
+// Close closes the replicas, the primary is left to its owner
+func (r *Router) Close() (err error) {
+	for _, replica := range r.Replicas {
+		if errClose := replica.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}
+
+	return
+}
@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func newStubDB(t *testing.T) *sql.DB {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRouterRoundRobin(t *testing.T) {
+	primary, replicas := newStubDB(t), []*sql.DB{newStubDB(t), newStubDB(t)}
+	router := repository.NewRouter(primary, replicas, time.Second, logrus.New())
+
+	// the replicas take no reads before a health check passed
+	assert.Equal(t, primary, router.Reader(context.TODO()))
+
+	router.CheckReplicas(context.TODO(), time.Second)
+	first, second := router.Reader(context.TODO()), router.Reader(context.TODO())
+	assert.ElementsMatch(t, replicas, []*sql.DB{first, second})
+	assert.Equal(t, first, router.Reader(context.TODO()))
+	assert.Equal(t, primary, router.Writer(context.TODO()))
+	assert.Equal(t, primary, router.Reader(repository.WithPrimary(context.TODO())))
+}
+
+func TestRouterEjectsReplica(t *testing.T) {
+	primary, healthy, down := newStubDB(t), newStubDB(t), newStubDB(t)
+	router := repository.NewRouter(primary, []*sql.DB{down, healthy}, time.Second, logrus.New())
+
+	router.CheckReplicas(context.TODO(), time.Second)
+	down.Close()
+	router.CheckReplicas(context.TODO(), time.Second)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, healthy, router.Reader(context.TODO()))
+	}
+
+	healthy.Close()
+	router.CheckReplicas(context.TODO(), time.Second)
+	assert.Equal(t, primary, router.Reader(context.TODO()))
+}
+
+func TestRouterReadYourWrites(t *testing.T) {
+	primary, replica := newStubDB(t), newStubDB(t)
+	router := repository.NewRouter(primary, []*sql.DB{replica}, 50*time.Millisecond, logrus.New())
+	router.CheckReplicas(context.TODO(), time.Second)
+
+	writer := domain.NewContextWithRequestInfo(context.TODO(), domain.RequestInfo{Actor: "editor", IP: "10.0.0.1"})
+	other := domain.NewContextWithRequestInfo(context.TODO(), domain.RequestInfo{Actor: "anonymous", IP: "10.0.0.2"})
+
+	router.Writer(writer)
+	assert.Equal(t, primary, router.Reader(writer))
+	assert.Equal(t, replica, router.Reader(other))
+	// the reads made outside of a request belong to no client
+	assert.Equal(t, replica, router.Reader(context.TODO()))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, replica, router.Reader(writer))
+}
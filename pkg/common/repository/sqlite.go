@@ -0,0 +1,12 @@
+package repository
+
+import "time"
+
+// sqliteTimeFormat is fixed width, so that the text sqlite stores sorts and compares in chronological order
+const sqliteTimeFormat = "2006-01-02 15:04:05.000"
+
+// SQLiteTime formats t as stored in the DATETIME columns of sqlite, in UTC to the millisecond
+// like the cursors. The driver reads the columns back as time.Time.
+func SQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// ErrStmtCacheClosed is returned by StmtCache.Prepare once the cache is closed
+var ErrStmtCacheClosed = errors.New("statement cache is closed")
+
+// StmtCache prepares each query once per database and keeps the statement until Close.
+// database/sql prepares a cached statement again on every connection it runs on, the first
+// time it runs there.
+type StmtCache struct {
+	Router *Router
+
+	mu     sync.Mutex
+	stmts  map[stmtKey]*sql.Stmt
+	closed bool
+}
+
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+// NewStmtCache will create a statement cache for the databases of router
+func NewStmtCache(router *Router) *StmtCache {
+	return &StmtCache{
+		Router: router,
+		stmts:  make(map[stmtKey]*sql.Stmt),
+	}
+}
+
+// PrepareRead returns the statement of a query reading rows, on the database the router picks
+// for reads. Within a transaction it is the statement of Prepare.
+func (c *StmtCache) PrepareRead(ctx context.Context, query string) (*sql.Stmt, error) {
+	if _, inTx := ctx.Value(txKey{}).(*sql.Tx); inTx {
+		return c.Prepare(ctx, query)
+	}
+
+	return c.prepare(ctx, c.Router.Reader(ctx), query)
+}
+
+// Prepare returns the statement of query on the primary, the reads of the client then go to the
+// primary for the read-your-writes window. Within the transaction carried by ctx the statement
+// is bound to that transaction, which closes the bound statement when it ends.
+func (c *StmtCache) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	db := c.Router.Writer(ctx)
+
+	tx, inTx := ctx.Value(txKey{}).(*sql.Tx)
+	if !inTx {
+		return c.prepare(ctx, db, query)
+	}
+
+	stmt, err := c.lookup(stmtKey{db, query})
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt == nil {
+		if !spareConn(db) {
+			// the transaction may hold the last connection, preparing on the pool would wait for it
+			return tx.PrepareContext(ctx, query)
+		}
+
+		if stmt, err = c.prepare(ctx, db, query); err != nil {
+			return nil, err
+		}
+	}
+
+	return tx.StmtContext(ctx, stmt), nil
+}
+
+func (c *StmtCache) prepare(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	key := stmtKey{db, query}
+
+	stmt, err := c.lookup(key)
+	if err != nil || stmt != nil {
+		return stmt, err
+	}
+
+	stmt, err = db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.store(key, stmt)
+}
+
+func (c *StmtCache) lookup(key stmtKey) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrStmtCacheClosed
+	}
+
+	return c.stmts[key], nil
+}
+
+// store keeps stmt unless a concurrent call stored the key first, the statement kept is returned
+func (c *StmtCache) store(key stmtKey, stmt *sql.Stmt) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		stmt.Close()
+		return nil, ErrStmtCacheClosed
+	}
+
+	if cached, ok := c.stmts[key]; ok {
+		stmt.Close()
+		return cached, nil
+	}
+
+	c.stmts[key] = stmt
+	return stmt, nil
+}
+
+// spareConn reports whether the pool can hand out a connection without waiting for one to be released
+func spareConn(db *sql.DB) bool {
+	stats := db.Stats()
+	return stats.Idle > 0 || stats.MaxOpenConnections == 0 || stats.OpenConnections < stats.MaxOpenConnections
+}
+
+// Close closes every cached statement, the cache cannot be used afterwards
+func (c *StmtCache) Close() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for key, stmt := range c.stmts {
+		errClose := stmt.Close()
+		if errClose != nil && err == nil {
+			err = errClose
+		}
+		delete(c.stmts, key)
+	}
+
+	return
+}
@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestStmtCachePreparesOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	prep := mock.ExpectPrepare("DELETE FROM post").WillBeClosed()
+	prep.ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	stmts := repository.NewStmtCache(repository.NewRouter(db, nil, 0, nil))
+	for _, id := range []int{1, 2} {
+		stmt, err := stmts.Prepare(context.TODO(), "DELETE FROM post WHERE id = ?")
+		require.NoError(t, err)
+		_, err = stmt.ExecContext(context.TODO(), id)
+		require.NoError(t, err)
+	}
+
+	assert.NoError(t, stmts.Close())
+	_, err = stmts.Prepare(context.TODO(), "DELETE FROM post WHERE id = ?")
+	assert.Equal(t, repository.ErrStmtCacheClosed, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStmtCacheWithinTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// the transaction holds the only connection, the statement is prepared on the transaction
+	db.SetMaxOpenConns(1)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM post").ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	stmts := repository.NewStmtCache(repository.NewRouter(db, nil, 0, nil))
+	tm := repository.NewSQLTxManager(db, newDialect(t, "postgres"), logrus.New())
+	err = tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+		stmt, err := stmts.Prepare(ctx, "DELETE FROM post WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx, 1)
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// BenchmarkStmtCache compares preparing the post lookup on every call, as the repositories
+// used to, with running the statement kept by a StmtCache
+func BenchmarkStmtCache(b *testing.B) {
+	db, _ := repositorytest.OpenDatabase(b, "sqlite")
+	_, err := db.Exec(`INSERT INTO author (name, created_at, updated_at) VALUES ('Dummy User', '2021-01-01 00:00:00.000', '2021-01-01 00:00:00.000')`)
+	require.NoError(b, err)
+	_, err = db.Exec(`INSERT INTO post (title, content, author_id, created_at, updated_at) VALUES ('Judul', 'Content', 1, '2021-01-01 00:00:00.000', '2021-01-01 00:00:00.000')`)
+	require.NoError(b, err)
+
+	query := `SELECT id, title, content, author_id, updated_at, created_at FROM "post" WHERE id = ?`
+	ctx := context.TODO()
+
+	b.Run("PerCall", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			stmt, err := db.PrepareContext(ctx, query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = scanPost(stmt.QueryRowContext(ctx, 1)); err != nil {
+				b.Fatal(err)
+			}
+			stmt.Close()
+		}
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		stmts := repository.NewStmtCache(repository.NewRouter(db, nil, 0, nil))
+		defer stmts.Close()
+
+		for i := 0; i < b.N; i++ {
+			stmt, err := stmts.Prepare(ctx, query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = scanPost(stmt.QueryRowContext(ctx, 1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func scanPost(row *sql.Row) error {
+	var (
+		id, authorID         int64
+		title, content       string
+		updatedAt, createdAt time.Time
+	)
+	return row.Scan(&id, &title, &content, &authorID, &updatedAt, &createdAt)
+}
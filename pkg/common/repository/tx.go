@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// Executor is the subset of *sql.DB and *sql.Tx used by the repositories
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// ExecutorFromContext returns the transaction carried by ctx, or db when there is none
+func ExecutorFromContext(ctx context.Context, db *sql.DB) Executor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// InTx reports whether ctx carries a transaction of a sql TxManager
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return ok
+}
+
+// txAttempts bounds the runs of a transaction the database keeps failing to serialize
+const txAttempts = 3
+
+// txRetryDelay is the wait before the second run of a transaction, it grows with every attempt
+const txRetryDelay = 10 * time.Millisecond
+
+// savepointKey carries the number of savepoints open in the transaction of the context
+type savepointKey struct{}
+
+type sqlTxManager struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Logger  logrus.FieldLogger
+}
+
+// NewSQLTxManager will create an implementation of domain.TxManager backed by database/sql.
+// Nested calls run within a savepoint of the outer transaction, and the outermost call runs
+// the transaction again when the dialect reports a serialization failure.
+func NewSQLTxManager(db *sql.DB, dialect Dialect, logger logrus.FieldLogger) domain.TxManager {
+	return &sqlTxManager{
+		DB:      db,
+		Dialect: dialect,
+		Logger:  logger,
+	}
+}
+
+func (m *sqlTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return m.withinSavepoint(ctx, tx, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = m.run(ctx, fn)
+		if err == nil || attempt == txAttempts || !m.Dialect.SerializationFailure(err) {
+			return
+		}
+
+		logging.FromContext(ctx, m.Logger).WithError(err).WithField("attempt", attempt).Warn("retrying transaction")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func (m *sqlTxManager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			logging.FromContext(ctx, m.Logger).WithError(errRollback).Error("rolling back transaction")
+		}
+		return
+	}
+
+	return tx.Commit()
+}
+
+// withinSavepoint runs fn in a savepoint of tx, a failing fn only undoes its own changes
+func (m *sqlTxManager) withinSavepoint(ctx context.Context, tx *sql.Tx, fn func(ctx context.Context) error) (err error) {
+	depth, _ := ctx.Value(savepointKey{}).(int)
+	depth++
+	savepoint := m.Dialect.Quote("sp_" + strconv.Itoa(depth))
+
+	_, err = tx.ExecContext(ctx, "SAVEPOINT "+savepoint)
+	if err != nil {
+		return
+	}
+
+	err = fn(context.WithValue(ctx, savepointKey{}, depth))
+	if err != nil {
+		_, errRollback := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+		if errRollback != nil {
+			logging.FromContext(ctx, m.Logger).WithError(errRollback).Error("rolling back to savepoint")
+		}
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return
+}
+
+type noopTxManager struct{}
+
+// NewNoopTxManager will create an implementation of domain.TxManager for the repositories without
+// transactions, such as the in-memory ones. fn runs as is and nothing is rolled back when it fails.
+func NewNoopTxManager() domain.TxManager {
+	return noopTxManager{}
+}
+
+func (noopTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,196 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestWithinTxCommit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM post").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tm := repository.NewSQLTxManager(db, newDialect(t, "postgres"), logrus.New())
+	err = tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+		_, err := repository.ExecutorFromContext(ctx, db).ExecContext(ctx, "DELETE FROM post")
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithinTxRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SAVEPOINT "sp_1"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`ROLLBACK TO SAVEPOINT "sp_1"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	errExpected := errors.New("Unexpected Error")
+	tm := repository.NewSQLTxManager(db, newDialect(t, "postgres"), logrus.New())
+	err = tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+		// nested calls run in a savepoint of the outer transaction
+		return tm.WithinTx(ctx, func(ctx context.Context) error {
+			return errExpected
+		})
+	})
+
+	assert.Equal(t, errExpected, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithinTxSavepoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SAVEPOINT "sp_1"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`SAVEPOINT "sp_2"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM post").WillReturnError(errors.New("Unexpected Error"))
+	mock.ExpectExec(regexp.QuoteMeta(`ROLLBACK TO SAVEPOINT "sp_2"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`RELEASE SAVEPOINT "sp_1"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tm := repository.NewSQLTxManager(db, newDialect(t, "postgres"), logrus.New())
+	err = tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+		return tm.WithinTx(ctx, func(ctx context.Context) error {
+			// the failed delete is undone, the outer transaction goes on
+			err := tm.WithinTx(ctx, func(ctx context.Context) error {
+				_, err := repository.ExecutorFromContext(ctx, db).ExecContext(ctx, "DELETE FROM post")
+				return err
+			})
+			assert.Error(t, err)
+			return nil
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithinTxSavepointSQLite(t *testing.T) {
+	db, d := repositorytest.OpenDatabase(t, "sqlite")
+	insert := `INSERT INTO author (name) VALUES (?)`
+
+	tm := repository.NewSQLTxManager(db, d, logrus.New())
+	err := tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+		_, err := repository.ExecutorFromContext(ctx, db).ExecContext(ctx, insert, "kept")
+		if err != nil {
+			return err
+		}
+
+		errExpected := errors.New("Unexpected Error")
+		err = tm.WithinTx(ctx, func(ctx context.Context) error {
+			_, err := repository.ExecutorFromContext(ctx, db).ExecContext(ctx, insert, "undone")
+			if err != nil {
+				return err
+			}
+			return errExpected
+		})
+		assert.Equal(t, errExpected, err)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	var names []string
+	rows, err := db.Query(`SELECT name FROM author`)
+	assert.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		assert.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"kept"}, names)
+}
+
+func TestWithinTxRetry(t *testing.T) {
+	cases := []struct {
+		kind  string
+		err   error
+		runs  int
+		retry bool
+	}{
+		{kind: "postgres", err: &pq.Error{Code: "40001"}, runs: 3, retry: true},
+		{kind: "postgres", err: &pq.Error{Code: "40P01"}, runs: 3, retry: true},
+		{kind: "postgres", err: &pq.Error{Code: "23505"}, runs: 1},
+		{kind: "mysql", err: &mysql.MySQLError{Number: 1213}, runs: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.kind+" "+tc.err.Error(), func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+
+			for i := 0; i < tc.runs; i++ {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			}
+
+			runs := 0
+			tm := repository.NewSQLTxManager(db, newDialect(t, tc.kind), logrus.New())
+			err = tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+				runs++
+				return tc.err
+			})
+
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.runs, runs)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("commit after a serialization failure", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		tm := repository.NewSQLTxManager(db, newDialect(t, "postgres"), logrus.New())
+		err = tm.WithinTx(context.TODO(), func(ctx context.Context) error {
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func newDialect(t *testing.T, kind string) repository.Dialect {
+	d, err := repository.DialectFor(kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
@@ -0,0 +1,32 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type auditRepository struct {
+	next domain.AuditRepository
+	r    *Retrier
+}
+
+// NewAuditRepository will wrap a domain.AuditRepository, retrying its reads
+func NewAuditRepository(next domain.AuditRepository, r *Retrier) domain.AuditRepository {
+	return &auditRepository{
+		next: next,
+		r:    r,
+	}
+}
+
+func (a *auditRepository) Store(ctx context.Context, event *domain.AuditEvent) error {
+	return a.next.Store(ctx, event)
+}
+
+func (a *auditRepository) Fetch(ctx context.Context, entity string, entityID int64, cursor string, num int64) (res []domain.AuditEvent, nextCursor string, err error) {
+	err = a.r.Do(ctx, "audit", "Fetch", func() (err error) {
+		res, nextCursor, err = a.next.Fetch(ctx, entity, entityID, cursor, num)
+		return
+	})
+	return
+}
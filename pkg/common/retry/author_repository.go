@@ -0,0 +1,32 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type authorRepository struct {
+	next domain.AuthorRepository
+	r    *Retrier
+}
+
+// NewAuthorRepository will wrap a domain.AuthorRepository, retrying its reads
+func NewAuthorRepository(next domain.AuthorRepository, r *Retrier) domain.AuthorRepository {
+	return &authorRepository{
+		next: next,
+		r:    r,
+	}
+}
+
+func (a *authorRepository) GetByID(ctx context.Context, id int64) (res domain.Author, err error) {
+	err = a.r.Do(ctx, "author", "GetByID", func() (err error) {
+		res, err = a.next.GetByID(ctx, id)
+		return
+	})
+	return
+}
+
+func (a *authorRepository) Store(ctx context.Context, author *domain.Author) error {
+	return a.next.Store(ctx, author)
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type postRepository struct {
+	next domain.PostRepository
+	r    *Retrier
+}
+
+// NewPostRepository will wrap a domain.PostRepository, retrying its reads. Store and Delete run
+// once, running them again after a lost connection could insert the post twice or report a post
+// deleted by the first attempt as not found. Update runs once as well: the usecase always calls
+// it within a transaction, where nothing is retried, and the transaction holding it also writes
+// the audit event, which is not safe to write twice.
+func NewPostRepository(next domain.PostRepository, r *Retrier) domain.PostRepository {
+	return &postRepository{
+		next: next,
+		r:    r,
+	}
+}
+
+func (p *postRepository) Store(ctx context.Context, post *domain.Post) error {
+	return p.next.Store(ctx, post)
+}
+
+func (p *postRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	err = p.r.Do(ctx, "post", "Fetch", func() (err error) {
+		res, nextCursor, err = p.next.Fetch(ctx, cursor, num)
+		return
+	})
+	return
+}
+
+func (p *postRepository) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	err = p.r.Do(ctx, "post", "GetByID", func() (err error) {
+		res, err = p.next.GetByID(ctx, id)
+		return
+	})
+	return
+}
+
+func (p *postRepository) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	err = p.r.Do(ctx, "post", "GetByTitle", func() (err error) {
+		res, err = p.next.GetByTitle(ctx, title)
+		return
+	})
+	return
+}
+
+func (p *postRepository) Update(ctx context.Context, post *domain.Post) error {
+	return p.next.Update(ctx, post)
+}
+
+func (p *postRepository) Delete(ctx context.Context, id int64) error {
+	return p.next.Delete(ctx, id)
+}
@@ -0,0 +1,93 @@
+// Package retry runs the idempotent repository calls again when they fail with a transient
+// database error. The calls are retried by decorators wrapping the domain interfaces.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+)
+
+// Policy bounds the attempts of a call and the backoff between them
+type Policy struct {
+	// Attempts of a call, the first one included, 1 never retries
+	Attempts int
+	// BaseDelay is the backoff after the first attempt, it doubles after each attempt up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Observer is told about every retry, metrics.Metrics counts them
+type Observer interface {
+	ObserveRetry(repository, method string)
+}
+
+// Retrier runs calls under a Policy, retrying the errors Transient accepts
+type Retrier struct {
+	Policy    Policy
+	Transient func(err error) bool
+	// Observer may be nil
+	Observer Observer
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewRetrier will create a retrier, transient classifies the errors, e.g. repository.Dialect.Transient
+func NewRetrier(policy Policy, transient func(err error) bool, observer Observer) *Retrier {
+	return &Retrier{
+		Policy:    policy,
+		Transient: transient,
+		Observer:  observer,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Do runs fn until it succeeds, fails with an error that is not transient, the attempts are spent,
+// or the next backoff would outlive the deadline of ctx. Within a transaction fn runs once: the
+// failed statement aborted the transaction, which the TxManager runs again as a whole.
+func (r *Retrier) Do(ctx context.Context, repo, method string, fn func() error) (err error) {
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= r.Policy.Attempts || !r.Transient(err) || repository.InTx(ctx) {
+			return
+		}
+
+		delay := r.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if r.Observer != nil {
+			r.Observer.ObserveRetry(repo, method)
+		}
+	}
+}
+
+// backoff is a random delay up to the exponential backoff of the attempt, the full jitter
+// spreads the retries of the calls that failed together
+func (r *Retrier) backoff(attempt int) time.Duration {
+	ceiling := r.Policy.MaxDelay
+	if shift := uint(attempt - 1); shift < 32 && r.Policy.BaseDelay<<shift < ceiling {
+		ceiling = r.Policy.BaseDelay << shift
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return time.Duration(r.rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,131 @@
+package retry_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/retry"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var policy = retry.Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+
+func transient(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+type countingObserver map[string]int
+
+func (o countingObserver) ObserveRetry(repository, method string) {
+	o[repository+"."+method]++
+}
+
+// failing returns a call failing with the given errors in turn, then succeeding
+func failing(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestDo(t *testing.T) {
+	errFinal := errors.New("Unexpected Error")
+	cases := []struct {
+		name    string
+		errs    []error
+		err     error
+		calls   int
+		retries int
+	}{
+		{name: "success", calls: 1},
+		{name: "transient then success", errs: []error{driver.ErrBadConn, driver.ErrBadConn}, calls: 3, retries: 2},
+		{name: "final error", errs: []error{errFinal}, err: errFinal, calls: 1},
+		{name: "attempts spent", errs: []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn}, err: driver.ErrBadConn, calls: 3, retries: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			observer := countingObserver{}
+			r := retry.NewRetrier(policy, transient, observer)
+
+			calls := 0
+			err := r.Do(context.TODO(), "post", "GetByID", failing(&calls, tc.errs...))
+
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.calls, calls)
+			assert.Equal(t, tc.retries, observer["post.GetByID"])
+		})
+	}
+}
+
+func TestDoWithinDeadline(t *testing.T) {
+	r := retry.NewRetrier(retry.Policy{Attempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}, transient, nil)
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := r.Do(ctx, "post", "GetByID", failing(&calls, driver.ErrBadConn, driver.ErrBadConn))
+
+	// a backoff outliving the deadline is not waited for
+	assert.Equal(t, driver.ErrBadConn, err)
+	assert.Less(t, time.Since(start).Milliseconds(), int64(time.Second/time.Millisecond))
+	assert.LessOrEqual(t, calls, 2)
+}
+
+func TestDoWithinTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	d, err := repository.DialectFor("postgres")
+	require.NoError(t, err)
+	r := retry.NewRetrier(policy, transient, nil)
+
+	calls := 0
+	err = repository.NewSQLTxManager(db, d, logrus.New()).WithinTx(context.TODO(), func(ctx context.Context) error {
+		return r.Do(ctx, "post", "Update", failing(&calls, driver.ErrBadConn))
+	})
+
+	assert.Equal(t, driver.ErrBadConn, err)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostRepository(t *testing.T) {
+	next := new(mocks.PostRepository)
+	next.On("GetByID", mock.Anything, int64(12)).Return(domain.Post{}, driver.ErrBadConn).Once()
+	next.On("GetByID", mock.Anything, int64(12)).Return(domain.Post{ID: 12}, nil).Once()
+	next.On("Store", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(driver.ErrBadConn).Once()
+	next.On("Update", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(driver.ErrBadConn).Once()
+
+	observer := countingObserver{}
+	repo := retry.NewPostRepository(next, retry.NewRetrier(policy, transient, observer))
+
+	res, err := repo.GetByID(context.TODO(), 12)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), res.ID)
+
+	// a store may have been applied before the connection was lost
+	assert.Equal(t, driver.ErrBadConn, repo.Store(context.TODO(), &domain.Post{}))
+	// an update runs within the transaction of the usecase, along with its audit event
+	assert.Equal(t, driver.ErrBadConn, repo.Update(context.TODO(), &domain.Post{}))
+
+	assert.Equal(t, countingObserver{"post.GetByID": 1}, observer)
+	next.AssertExpectations(t)
+}
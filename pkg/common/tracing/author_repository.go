@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type authorRepository struct {
+	next   domain.AuthorRepository
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+// NewAuthorRepository will wrap a domain.AuthorRepository, opening a span around the query of every call.
+// These spans show the author lookups a post listing fans out to.
+func NewAuthorRepository(next domain.AuthorRepository, tp trace.TracerProvider, dbKind string) domain.AuthorRepository {
+	return &authorRepository{
+		next:   next,
+		tracer: tp.Tracer(InstrumentationName),
+		system: dbSystem(dbKind),
+	}
+}
+
+func (a *authorRepository) GetByID(ctx context.Context, id int64) (res domain.Author, err error) {
+	ctx, end := query(ctx, a.tracer, a.system, "AuthorRepository", "GetByID", "SELECT")
+	defer end(&err)
+	return a.next.GetByID(ctx, id)
+}
+
+func (a *authorRepository) Store(ctx context.Context, author *domain.Author) (err error) {
+	ctx, end := query(ctx, a.tracer, a.system, "AuthorRepository", "Store", "INSERT")
+	defer end(&err)
+	return a.next.Store(ctx, author)
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier adapts the fiber request headers to a propagation.TextMapCarrier
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Middleware opens a server span for every request, continuing the trace of the W3C traceparent header.
+// The span travels to the usecases in the context returned by delivery.RequestContext.
+func Middleware(tp trace.TracerProvider) fiber.Handler {
+	tracer := tp.Tracer(InstrumentationName)
+	propagator := propagation.TraceContext{}
+
+	return func(c *fiber.Ctx) error {
+		own := c.Route()
+		// the span outlives the request, fiber strings point into the reused request buffer
+		method := utils.ImmutableString(c.Method())
+
+		ctx := propagator.Extract(delivery.Context(c), headerCarrier{c})
+		ctx, span := tracer.Start(ctx, "HTTP "+method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(method),
+				semconv.HTTPTargetKey.String(utils.ImmutableString(c.OriginalURL())),
+			),
+		)
+		defer span.End()
+
+		delivery.SetContext(c, ctx)
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+			span.RecordError(err)
+		}
+
+		// still on the route of this middleware means no other route matched
+		if c.Route() != own {
+			span.SetName("HTTP " + method + " " + c.Route().Path)
+			span.SetAttributes(semconv.HTTPRouteKey.String(c.Route().Path))
+		}
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type postRepository struct {
+	next   domain.PostRepository
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+// NewPostRepository will wrap a domain.PostRepository, opening a span around the query of every call.
+// dbKind is the database.kind the repository runs on.
+func NewPostRepository(next domain.PostRepository, tp trace.TracerProvider, dbKind string) domain.PostRepository {
+	return &postRepository{
+		next:   next,
+		tracer: tp.Tracer(InstrumentationName),
+		system: dbSystem(dbKind),
+	}
+}
+
+func (p *postRepository) Store(ctx context.Context, post *domain.Post) (err error) {
+	ctx, end := query(ctx, p.tracer, p.system, "PostRepository", "Store", "INSERT")
+	defer end(&err)
+	return p.next.Store(ctx, post)
+}
+
+func (p *postRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	ctx, end := query(ctx, p.tracer, p.system, "PostRepository", "Fetch", "SELECT")
+	defer end(&err)
+	return p.next.Fetch(ctx, cursor, num)
+}
+
+func (p *postRepository) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	ctx, end := query(ctx, p.tracer, p.system, "PostRepository", "GetByID", "SELECT")
+	defer end(&err)
+	return p.next.GetByID(ctx, id)
+}
+
+func (p *postRepository) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	ctx, end := query(ctx, p.tracer, p.system, "PostRepository", "GetByTitle", "SELECT")
+	defer end(&err)
+	return p.next.GetByTitle(ctx, title)
+}
+
+func (p *postRepository) Update(ctx context.Context, post *domain.Post) (err error) {
+	ctx, end := query(ctx, p.tracer, p.system, "PostRepository", "Update", "UPDATE")
+	defer end(&err)
+	return p.next.Update(ctx, post)
+}
+
+func (p *postRepository) Delete(ctx context.Context, id int64) (err error) {
+	ctx, end := query(ctx, p.tracer, p.system, "PostRepository", "Delete", "DELETE")
+	defer end(&err)
+	return p.next.Delete(ctx, id)
+}
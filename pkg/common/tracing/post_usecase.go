@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type postUsecase struct {
+	next   domain.PostUsecase
+	tracer trace.Tracer
+}
+
+// NewPostUsecase will wrap a domain.PostUsecase, opening a span around every call
+func NewPostUsecase(next domain.PostUsecase, tp trace.TracerProvider) domain.PostUsecase {
+	return &postUsecase{
+		next:   next,
+		tracer: tp.Tracer(InstrumentationName),
+	}
+}
+
+func (p *postUsecase) Store(ctx context.Context, post *domain.Post) (err error) {
+	ctx, end := start(ctx, p.tracer, "PostUsecase", "Store")
+	defer end(&err)
+	return p.next.Store(ctx, post)
+}
+
+func (p *postUsecase) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	ctx, end := start(ctx, p.tracer, "PostUsecase", "Fetch")
+	defer end(&err)
+	return p.next.Fetch(ctx, cursor, num)
+}
+
+func (p *postUsecase) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	ctx, end := start(ctx, p.tracer, "PostUsecase", "GetByID")
+	defer end(&err)
+	return p.next.GetByID(ctx, id)
+}
+
+func (p *postUsecase) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	ctx, end := start(ctx, p.tracer, "PostUsecase", "GetByTitle")
+	defer end(&err)
+	return p.next.GetByTitle(ctx, title)
+}
+
+func (p *postUsecase) Update(ctx context.Context, post *domain.Post) (err error) {
+	ctx, end := start(ctx, p.tracer, "PostUsecase", "Update")
+	defer end(&err)
+	return p.next.Update(ctx, post)
+}
+
+func (p *postUsecase) Delete(ctx context.Context, id int64) (err error) {
+	ctx, end := start(ctx, p.tracer, "PostUsecase", "Delete")
+	defer end(&err)
+	return p.next.Delete(ctx, id)
+}
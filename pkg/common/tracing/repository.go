@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSystem maps database.kind to the db.system attribute
+func dbSystem(kind string) attribute.KeyValue {
+	switch kind {
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "mysql":
+		return semconv.DBSystemMySQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemKey.String(kind)
+	}
+}
+
+// query opens a client span around the SQL statement a repository method runs
+func query(ctx context.Context, tracer trace.Tracer, system attribute.KeyValue, component, method, operation string) (context.Context, func(*error)) {
+	return start(ctx, tracer, component, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(system, semconv.DBOperationKey.String(operation)),
+	)
+}
@@ -0,0 +1,63 @@
+// Package tracing records OpenTelemetry spans for the http requests, the usecase calls and the repository queries.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer of this service
+const InstrumentationName = "github.com/ilmimris/poc-gofiber-clean-arch"
+
+// NewProvider will create the tracer provider exporting to cfg.Exporter.
+// The returned shutdown flushes the pending spans, the none exporter returns a no-op provider.
+func NewProvider(ctx context.Context, cfg config.TracingConfig) (tp trace.TracerProvider, shutdown func(ctx context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case config.TracingExporterNone:
+		return trace.NewNoopTracerProvider(), func(context.Context) error { return nil }, nil
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		err = fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+
+	return provider, provider.Shutdown, nil
+}
+
+// start opens a span named after the component and the method, e.g. PostUsecase.Fetch
+func start(ctx context.Context, tracer trace.Tracer, component, method string, opts ...trace.SpanStartOption) (context.Context, func(*error)) {
+	ctx, span := tracer.Start(ctx, component+"."+method, opts...)
+
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
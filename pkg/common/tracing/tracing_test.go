@@ -0,0 +1,155 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/config"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/tracing"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	mocks "github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newRecorder() (*tracetest.SpanRecorder, trace.TracerProvider) {
+	sr := tracetest.NewSpanRecorder()
+	return sr, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	sr, tp := newRecorder()
+
+	var handlerSpan trace.SpanContext
+	app := fiber.New()
+	app.Use(tracing.Middleware(tp))
+	app.Get("/posts/:id", func(c *fiber.Ctx) error {
+		handlerSpan = trace.SpanContextFromContext(delivery.RequestContext(c))
+		return c.SendStatus(http.StatusNotFound)
+	})
+
+	req, err := http.NewRequest("GET", "/posts/12", strings.NewReader(""))
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rec, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.StatusCode)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "HTTP GET /posts/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, int64(http.StatusNotFound), attr(span, "http.status_code").AsInt64())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	// the usecases get the request span as their parent
+	assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+}
+
+func TestMiddlewareUnmatched(t *testing.T) {
+	sr, tp := newRecorder()
+
+	app := fiber.New()
+	app.Use(tracing.Middleware(tp))
+
+	req, err := http.NewRequest("GET", "/wp-login.php", strings.NewReader(""))
+	require.NoError(t, err)
+
+	_, err = app.Test(req, -1)
+	require.NoError(t, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "HTTP GET", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+}
+
+func TestPostUsecase(t *testing.T) {
+	sr, tp := newRecorder()
+
+	mockUCase := new(mocks.PostUsecase)
+	mockUCase.On("GetByID", mock.Anything, int64(1)).Return(domain.Post{ID: 1}, nil).Once()
+	mockUCase.On("GetByID", mock.Anything, int64(2)).Return(domain.Post{}, domain.ErrNotFound).Once()
+
+	u := tracing.NewPostUsecase(mockUCase, tp)
+
+	res, err := u.GetByID(context.TODO(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.ID)
+
+	_, err = u.GetByID(context.TODO(), 2)
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "PostUsecase.GetByID", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, domain.ErrNotFound.Error(), spans[1].Status().Description)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestPostRepository(t *testing.T) {
+	sr, tp := newRecorder()
+
+	mockAuthorRepo := new(mocks.AuthorRepository)
+	mockAuthorRepo.On("GetByID", mock.Anything, int64(7)).Return(domain.Author{ID: 7}, nil).Once()
+
+	mockPostRepo := new(mocks.PostRepository)
+	authorRepo := tracing.NewAuthorRepository(mockAuthorRepo, tp, "postgres")
+	mockPostRepo.On("Fetch", mock.Anything, "", int64(1)).Return([]domain.Post{{ID: 1}}, "1", nil).Run(func(args mock.Arguments) {
+		// a nested query becomes a child of the span of the outer one
+		_, err := authorRepo.GetByID(args.Get(0).(context.Context), 7)
+		assert.NoError(t, err)
+	}).Once()
+
+	r := tracing.NewPostRepository(mockPostRepo, tp, "postgres")
+
+	res, _, err := r.Fetch(context.TODO(), "", 1)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	author, post := spans[0], spans[1]
+	assert.Equal(t, "AuthorRepository.GetByID", author.Name())
+	assert.Equal(t, "PostRepository.Fetch", post.Name())
+	assert.Equal(t, post.SpanContext().SpanID(), author.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, post.SpanKind())
+	assert.Equal(t, "postgresql", attr(post, "db.system").AsString())
+	assert.Equal(t, "SELECT", attr(post, "db.operation").AsString())
+	mockPostRepo.AssertExpectations(t)
+	mockAuthorRepo.AssertExpectations(t)
+}
+
+func TestNewProviderNone(t *testing.T) {
+	tp, shutdown, err := tracing.NewProvider(context.TODO(), config.TracingConfig{Exporter: config.TracingExporterNone})
+	require.NoError(t, err)
+
+	_, span := tp.Tracer(tracing.InstrumentationName).Start(context.TODO(), "noop")
+	assert.False(t, span.IsRecording())
+	assert.NoError(t, shutdown(context.TODO()))
+}
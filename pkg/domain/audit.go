@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Audit actions recorded for every mutation
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditEntityPost is the entity name used when auditing posts
+const AuditEntityPost = "post"
+
+// AuditEvent represent a single recorded mutation of an entity
+type AuditEvent struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  int64           `json:"entity_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	RequestID string          `json:"request_id"`
+	IP        string          `json:"ip"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditUsecase represent the audit's usecase contract
+type AuditUsecase interface {
+	// Read
+	Fetch(ctx context.Context, entity string, entityID int64, cursor string, num int64) ([]AuditEvent, string, error)
+}
+
+// AuditRepository represent the audit's repository contract
+type AuditRepository interface {
+	// Create
+	Store(ctx context.Context, e *AuditEvent) error
+
+	// Read
+	Fetch(ctx context.Context, entity string, entityID int64, cursor string, num int64) (res []AuditEvent, nextCursor string, err error)
+}
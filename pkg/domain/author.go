@@ -23,4 +23,7 @@ type AuthorUsecase interface {
 type AuthorRepository interface {
 	// Read
 	GetByID(ctx context.Context, id int64) (Author, error)
+
+	// Write
+	Store(ctx context.Context, a *Author) error
 }
@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrInternalServerError will throw if any the Internal Server Error happen
@@ -11,4 +14,20 @@ var (
 	ErrConflict = errors.New("Your Item already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
 	ErrBadParamInput = errors.New("Given Param is not valid")
+	// ErrUnavailable will throw if the storage is failing and the request is turned away without trying it
+	ErrUnavailable = errors.New("Service is temporarily unavailable")
 )
+
+// UnavailableError is an ErrUnavailable telling when the request is worth trying again
+type UnavailableError struct {
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return ErrUnavailable.Error()
+}
+
+// Is makes errors.Is(err, ErrUnavailable) hold
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
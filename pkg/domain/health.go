@@ -0,0 +1,31 @@
+package domain
+
+import "context"
+
+// Health statuses reported by the health checks
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// HealthChecker represent a dependency the service needs to be ready, such as the database
+type HealthChecker interface {
+	// Name identifies the check in the readiness report
+	Name() string
+	// Check returns an error when the dependency is not usable, it must honour ctx's deadline
+	Check(ctx context.Context) error
+}
+
+// HealthCheckResult represent the outcome of one HealthChecker
+type HealthCheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport represent the outcome of every registered HealthChecker
+type HealthReport struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
@@ -34,3 +34,17 @@ func (_m *AuthorRepository) GetByID(ctx context.Context, id int64) (domain.Autho
 
 	return r0, r1
 }
+
+// Store provides a mock function with given fields: ctx, a
+func (_m *AuthorRepository) Store(ctx context.Context, a *domain.Author) error {
+	ret := _m.Called(ctx, a)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Author) error); ok {
+		r0 = rf(ctx, a)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -34,7 +34,7 @@ type PostUsecase interface {
 
 // PostRepository represent the post's repository contract
 type PostRepository interface {
-	// Create
+	// Create, the timestamps left zero are set to the time of the insert
 	Store(ctx context.Context, p *Post) error
 
 	// Read
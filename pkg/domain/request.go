@@ -0,0 +1,23 @@
+package domain
+
+import "context"
+
+// RequestInfo represent who issued the current request and from where
+type RequestInfo struct {
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+type requestInfoKey struct{}
+
+// NewContextWithRequestInfo returns a copy of ctx carrying the given request info
+func NewContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request info carried by ctx, if any
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "context"
+
+// TxManager represent the transaction contract shared by repositories
+type TxManager interface {
+	// WithinTx runs fn inside a transaction carried by the given context.
+	// The transaction is committed when fn returns nil and rolled back otherwise.
+	// A nested call only rolls back its own changes. fn may run again when the
+	// database aborts the transaction to keep it serializable.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
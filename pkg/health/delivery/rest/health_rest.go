@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+// ReadinessChecker runs the readiness checks
+type ReadinessChecker interface {
+	Check(ctx context.Context) domain.HealthReport
+}
+
+// HealthHandler represent the rest handler for the probes
+type HealthHandler struct {
+	Readiness ReadinessChecker
+}
+
+// NewHealthHandler will initialize the liveness and readiness endpoints
+func NewHealthHandler(app *fiber.App, r ReadinessChecker) {
+	handler := &HealthHandler{
+		Readiness: r,
+	}
+
+	app.Get("/healthz", handler.Healthz)
+	app.Get("/readyz", handler.Readyz)
+}
+
+// Healthz will report the process as alive, without checking any dependency
+func (hh *HealthHandler) Healthz(c *fiber.Ctx) error {
+	c.Response().SetStatusCode(http.StatusOK)
+	return c.JSON(domain.HealthReport{Status: domain.HealthStatusOK, Checks: []domain.HealthCheckResult{}})
+}
+
+// Readyz will run the readiness checks, answering 503 when one of them fails
+func (hh *HealthHandler) Readyz(c *fiber.Ctx) error {
+	report := hh.Readiness.Check(delivery.RequestContext(c))
+
+	status := http.StatusOK
+	if report.Status != domain.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.Response().SetStatusCode(status)
+	return c.JSON(report)
+}
@@ -0,0 +1,69 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	healthRest "github.com/ilmimris/poc-gofiber-clean-arch/pkg/health/delivery/rest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubReadiness domain.HealthReport
+
+func (s stubReadiness) Check(ctx context.Context) domain.HealthReport {
+	return domain.HealthReport(s)
+}
+
+func TestHealthz(t *testing.T) {
+	e := fiber.New()
+	healthRest.NewHealthHandler(e, stubReadiness{Status: domain.HealthStatusFail})
+
+	req, err := http.NewRequest("GET", "/healthz", nil)
+	assert.NoError(t, err)
+	rec, err := e.Test(req, -1)
+	require.NoError(t, err)
+
+	// liveness does not depend on the readiness checks
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+}
+
+func TestReadyz(t *testing.T) {
+	report := stubReadiness{
+		Status: domain.HealthStatusFail,
+		Checks: []domain.HealthCheckResult{
+			{Name: "database", Status: domain.HealthStatusFail, LatencyMs: 3, Error: "connection refused"},
+		},
+	}
+
+	e := fiber.New()
+	healthRest.NewHealthHandler(e, report)
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	assert.NoError(t, err)
+	rec, err := e.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.StatusCode)
+
+	var body domain.HealthReport
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, domain.HealthReport(report), body)
+}
+
+func TestReadyzOK(t *testing.T) {
+	e := fiber.New()
+	healthRest.NewHealthHandler(e, stubReadiness{Status: domain.HealthStatusOK})
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	assert.NoError(t, err)
+	rec, err := e.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.StatusCode)
+}
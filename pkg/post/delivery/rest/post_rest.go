@@ -1,12 +1,17 @@
 package rest
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/delivery"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
 	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
 
 	validator "gopkg.in/go-playground/validator.v9"
 )
@@ -20,12 +25,14 @@ type ResponseError struct {
 // PostHandler represent the rest handler for post
 type PostHandler struct {
 	PUsecase domain.PostUsecase
+	Logger   logrus.FieldLogger
 }
 
 // NewPostHandler will initialize the post resource endpoint
-func NewPostHandler(app *fiber.App, p domain.PostUsecase) {
+func NewPostHandler(app *fiber.App, p domain.PostUsecase, logger logrus.FieldLogger) {
 	handler := &PostHandler{
 		PUsecase: p,
+		Logger:   logger,
 	}
 
 	app.Get("/posts", handler.FetchPost)
@@ -45,13 +52,17 @@ func (ph *PostHandler) Store(c *fiber.Ctx) (err error) {
 
 	var ok bool
 	if ok, err = isRequestValid(&post); !ok {
+		c.Response().SetStatusCode(http.StatusBadRequest)
 		return c.JSON(ResponseError{Error: http.StatusBadRequest, Message: err.Error()})
 	}
 
-	ctx := c.Context()
+	// a new post is stamped with the time it is stored, whatever the client sent
+	post.CreatedAt, post.UpdatedAt = time.Time{}, time.Time{}
+
+	ctx := delivery.RequestContext(c)
 	err = ph.PUsecase.Store(ctx, &post)
 	if err != nil {
-		return c.JSON(ResponseError{Error: getStatusCode(err), Message: err.Error()})
+		return ph.fail(ctx, c, err)
 	}
 
 	c.Response().SetStatusCode(http.StatusCreated)
@@ -63,12 +74,11 @@ func (ph *PostHandler) FetchPost(c *fiber.Ctx) error {
 	numS := c.Query("num")
 	num, _ := strconv.Atoi(numS)
 	cursor := c.Query("cursor")
-	ctx := c.Context()
+	ctx := delivery.RequestContext(c)
 
 	listAr, nextCursor, err := ph.PUsecase.Fetch(ctx, cursor, int64(num))
 	if err != nil {
-		c.Response().SetStatusCode(getStatusCode(err))
-		return c.JSON(ResponseError{Error: getStatusCode(err), Message: err.Error()})
+		return ph.fail(ctx, c, err)
 	}
 
 	c.Response().SetStatusCode(http.StatusOK)
@@ -85,12 +95,11 @@ func (ph *PostHandler) GetByID(c *fiber.Ctx) error {
 	}
 
 	id := int64(idP)
-	ctx := c.Context()
+	ctx := delivery.RequestContext(c)
 
 	post, err := ph.PUsecase.GetByID(ctx, id)
 	if err != nil {
-		c.Response().SetStatusCode(getStatusCode(err))
-		return c.JSON(ResponseError{Error: getStatusCode(err), Message: err.Error()})
+		return ph.fail(ctx, c, err)
 	}
 
 	c.Response().SetStatusCode(http.StatusOK)
@@ -106,12 +115,11 @@ func (ph *PostHandler) Delete(c *fiber.Ctx) error {
 	}
 
 	id := int64(idP)
-	ctx := c.Context()
+	ctx := delivery.RequestContext(c)
 
 	err = ph.PUsecase.Delete(ctx, id)
 	if err != nil {
-		c.Response().SetStatusCode(getStatusCode(err))
-		return c.JSON(ResponseError{Error: getStatusCode(err), Message: err.Error()})
+		return ph.fail(ctx, c, err)
 	}
 
 	return c.SendStatus(http.StatusNoContent)
@@ -128,12 +136,53 @@ func isRequestValid(p *domain.Post) (bool, error) {
 	return true, nil
 }
 
+// fail logs err with the fields of the request and sends it as the response
+func (ph *PostHandler) fail(ctx context.Context, c *fiber.Ctx, err error) error {
+	status := getStatusCode(err)
+	// drivers may report the deadline of the request with an error of their own
+	if ctx.Err() == context.DeadlineExceeded {
+		status = http.StatusGatewayTimeout
+	}
+
+	entry := logging.FromContext(ctx, ph.Logger).WithError(err)
+	if status >= http.StatusInternalServerError {
+		entry.Error("request failed")
+	} else {
+		entry.Info("request rejected")
+	}
+
+	if status == http.StatusServiceUnavailable {
+		c.Set(fiber.HeaderRetryAfter, retryAfter(err))
+	}
+
+	c.Response().SetStatusCode(status)
+	return c.JSON(ResponseError{Error: status, Message: err.Error()})
+}
+
+// retryAfter is the Retry-After header of an unavailable response, in whole seconds rounded up
+func retryAfter(err error) string {
+	seconds := int64(1)
+	var unavailable *domain.UnavailableError
+	if errors.As(err, &unavailable) && unavailable.RetryAfter > time.Second {
+		seconds = int64((unavailable.RetryAfter + time.Second - 1) / time.Second)
+	}
+
+	return strconv.FormatInt(seconds, 10)
+}
+
 func getStatusCode(err error) int {
 	if err == nil {
 		return http.StatusOK
 	}
 
-	log.Print(err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	if errors.Is(err, domain.ErrUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+
 	switch err {
 	case domain.ErrInternalServerError:
 		return http.StatusInternalServerError
@@ -141,6 +190,8 @@ func getStatusCode(err error) int {
 		return http.StatusNotFound
 	case domain.ErrConflict:
 		return http.StatusConflict
+	case domain.ErrBadParamInput:
+		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
 	}
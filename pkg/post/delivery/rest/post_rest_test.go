@@ -1,7 +1,9 @@
 package rest_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,11 +11,13 @@ import (
 	"time"
 
 	"github.com/bxcodec/faker"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/middleware/timeout"
 	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
 	mocks "github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
 	postRest "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/delivery/rest"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -35,7 +39,7 @@ func TestFetch(t *testing.T) {
 	req, err := http.NewRequest("GET", "/posts?num=1&cursor="+cursor, strings.NewReader(""))
 	assert.NoError(t, err)
 
-	postRest.NewPostHandler(e, mockUCase)
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
 	rec, err := e.Test(req, -1)
 
 	require.NoError(t, err)
@@ -56,7 +60,7 @@ func TestFetchError(t *testing.T) {
 	req, err := http.NewRequest("GET", "/posts?num=1&cursor="+cursor, strings.NewReader(""))
 	assert.NoError(t, err)
 
-	postRest.NewPostHandler(e, mockUCase)
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
 	rec, err := e.Test(req, -1)
 
 	require.NoError(t, err)
@@ -67,6 +71,22 @@ func TestFetchError(t *testing.T) {
 	mockUCase.AssertExpectations(t)
 }
 
+func TestFetchBadParam(t *testing.T) {
+	mockUCase := new(mocks.PostUsecase)
+	mockUCase.On("Fetch", mock.Anything, "", int64(-1)).Return(nil, "", domain.ErrBadParamInput)
+
+	e := fiber.New()
+	req, err := http.NewRequest("GET", "/posts?num=-1", strings.NewReader(""))
+	assert.NoError(t, err)
+
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
+	rec, err := e.Test(req, -1)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.StatusCode)
+	mockUCase.AssertExpectations(t)
+}
+
 func TestGetByID(t *testing.T) {
 	var mockPost domain.Post
 	err := faker.FakeData(&mockPost)
@@ -82,7 +102,7 @@ func TestGetByID(t *testing.T) {
 	req, err := http.NewRequest("GET", "/posts/"+strconv.Itoa(num), nil)
 	assert.NoError(t, err)
 
-	postRest.NewPostHandler(e, mockUCase)
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
 	rec, err := e.Test(req, -1)
 
 	require.NoError(t, err)
@@ -113,7 +133,7 @@ func TestStore(t *testing.T) {
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
-	postRest.NewPostHandler(e, mockUCase)
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
 	rec, err := e.Test(req, -1)
 
 	require.NoError(t, err)
@@ -137,7 +157,7 @@ func TestDelete(t *testing.T) {
 	req, err := http.NewRequest("DELETE", "/posts/"+strconv.Itoa(num), strings.NewReader(""))
 	assert.NoError(t, err)
 
-	postRest.NewPostHandler(e, mockUCase)
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
 	rec, err := e.Test(req, -1)
 
 	require.NoError(t, err)
@@ -146,3 +166,96 @@ func TestDelete(t *testing.T) {
 	mockUCase.AssertExpectations(t)
 
 }
+
+func TestStoreConflict(t *testing.T) {
+	mockPost := domain.Post{
+		Title:   "Title",
+		Content: "Content",
+	}
+	mockUCase := new(mocks.PostUsecase)
+
+	j, err := json.Marshal(mockPost)
+	assert.NoError(t, err)
+
+	mockUCase.On("Store", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(domain.ErrConflict)
+
+	e := fiber.New()
+	req, err := http.NewRequest("POST", "/posts", strings.NewReader(string(j)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
+	rec, err := e.Test(req, -1)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusConflict, rec.StatusCode)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestFetchTimeout(t *testing.T) {
+	mockUCase := new(mocks.PostUsecase)
+	mockUCase.On("Fetch", mock.Anything, "", int64(0)).Return(nil, "", errors.New("pq: canceling statement due to user request")).
+		Run(func(args mock.Arguments) {
+			// a query running past the deadline of the route is cancelled by the driver
+			<-args.Get(0).(context.Context).Done()
+		})
+
+	e := fiber.New()
+	e.Use(timeout.New(timeout.Config{
+		Timeout: time.Minute,
+		Routes:  map[string]time.Duration{"/posts": 10 * time.Millisecond},
+	}))
+	req, err := http.NewRequest("GET", "/posts", strings.NewReader(""))
+	assert.NoError(t, err)
+
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
+	rec, err := e.Test(req, -1)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.StatusCode)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestGetByIDDeadlineExceeded(t *testing.T) {
+	mockUCase := new(mocks.PostUsecase)
+	mockUCase.On("GetByID", mock.Anything, int64(12)).Return(domain.Post{}, context.DeadlineExceeded)
+
+	e := fiber.New()
+	req, err := http.NewRequest("GET", "/posts/12", strings.NewReader(""))
+	assert.NoError(t, err)
+
+	postRest.NewPostHandler(e, mockUCase, logrus.New())
+	rec, err := e.Test(req, -1)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.StatusCode)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestGetByIDUnavailable(t *testing.T) {
+	cases := []struct {
+		err        error
+		retryAfter string
+	}{
+		{err: &domain.UnavailableError{RetryAfter: 2500 * time.Millisecond}, retryAfter: "3"},
+		{err: domain.ErrUnavailable, retryAfter: "1"},
+	}
+
+	for _, tc := range cases {
+		mockUCase := new(mocks.PostUsecase)
+		mockUCase.On("GetByID", mock.Anything, int64(12)).Return(domain.Post{}, tc.err)
+
+		e := fiber.New()
+		req, err := http.NewRequest("GET", "/posts/12", strings.NewReader(""))
+		assert.NoError(t, err)
+
+		postRest.NewPostHandler(e, mockUCase, logrus.New())
+		rec, err := e.Test(req, -1)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.StatusCode)
+		assert.Equal(t, tc.retryAfter, rec.Header.Get("Retry-After"))
+		mockUCase.AssertExpectations(t)
+	}
+}
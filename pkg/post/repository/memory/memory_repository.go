@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+)
+
+type memoryPostRepo struct {
+	mu     sync.RWMutex
+	lastID int64
+	posts  map[int64]domain.Post
+}
+
+// NewMemoryPostRepository will create an implementation of post repository keeping the posts in memory
+func NewMemoryPostRepository() domain.PostRepository {
+	return &memoryPostRepo{
+		posts: make(map[int64]domain.Post),
+	}
+}
+
+// titleTaken tells whether another post than id already has the title, like the unique index of the sql schemas
+func (m *memoryPostRepo) titleTaken(title string, id int64) bool {
+	for _, p := range m.posts {
+		if p.Title == title && p.ID != id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *memoryPostRepo) Store(ctx context.Context, entry *domain.Post) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.titleTaken(entry.Title, 0) {
+		return domain.ErrConflict
+	}
+
+	// like the sql repositories the timestamps left zero are set on insert, at the precision of the cursors
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	m.lastID++
+	entry.ID = m.lastID
+	entry.CreatedAt = stamp(entry.CreatedAt, now)
+	entry.UpdatedAt = stamp(entry.UpdatedAt, now)
+
+	m.posts[entry.ID] = stored(*entry)
+	return
+}
+
+// stamp returns t in UTC at the precision of the cursors, or now when t is zero
+func stamp(t, now time.Time) time.Time {
+	if t.IsZero() {
+		return now
+	}
+
+	return t.UTC().Truncate(time.Millisecond)
+}
+
+func (m *memoryPostRepo) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	createdAt, id, err := repository.DecodeCursor(cursor)
+	if err != nil && cursor != "" {
+		return nil, "", domain.ErrBadParamInput
+	}
+	err = nil
+
+	res = make([]domain.Post, 0)
+	if num <= 0 {
+		return
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, p := range m.posts {
+		if p.CreatedAt.After(createdAt) || (p.CreatedAt.Equal(createdAt) && p.ID > id) {
+			res = append(res, p)
+		}
+	}
+
+	// ORDER BY created_at, the id breaks the ties to keep the pages stable
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CreatedAt.Equal(res[j].CreatedAt) {
+			return res[i].ID < res[j].ID
+		}
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+
+	if int64(len(res)) > num {
+		res = res[:num]
+	}
+
+	if len(res) == int(num) {
+		last := res[len(res)-1]
+		nextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return
+}
+
+func (m *memoryPostRepo) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res, ok := m.posts[id]
+	if !ok {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (m *memoryPostRepo) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, p := range m.posts {
+		if p.Title == title {
+			return p, nil
+		}
+	}
+
+	return res, domain.ErrNotFound
+}
+
+func (m *memoryPostRepo) Update(ctx context.Context, entry *domain.Post) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.posts[entry.ID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+
+	if m.titleTaken(entry.Title, entry.ID) {
+		return domain.ErrConflict
+	}
+
+	// the columns the sql repositories update, the creation time is kept
+	current.Title = entry.Title
+	current.Content = entry.Content
+	current.Author = domain.Author{ID: entry.Author.ID}
+	current.UpdatedAt = entry.UpdatedAt
+
+	m.posts[entry.ID] = current
+	return
+}
+
+func (m *memoryPostRepo) Delete(ctx context.Context, id int64) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[id]; !ok {
+		return domain.ErrNotFound
+	}
+
+	delete(m.posts, id)
+	return
+}
+
+// stored keeps only what the sql repositories persist, the author is read back as its id
+func stored(p domain.Post) domain.Post {
+	p.Author = domain.Author{ID: p.Author.ID}
+	return p
+}
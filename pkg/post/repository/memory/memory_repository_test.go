@@ -0,0 +1,79 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	postRepo "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostRepositorySuite(t *testing.T) {
+	repositorytest.RunPostRepositorySuite(t, func(t *testing.T) domain.PostRepository {
+		return postRepo.NewMemoryPostRepository()
+	})
+}
+
+func TestFetchTies(t *testing.T) {
+	a := postRepo.NewMemoryPostRepository()
+
+	// stored within the same millisecond, the pages still never overlap nor skip a post
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, a.Store(context.TODO(), &domain.Post{Title: fmt.Sprintf("post %d", i)}))
+	}
+
+	var titles []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+
+		list, next, err := a.Fetch(context.TODO(), cursor, 2)
+		require.NoError(t, err)
+		for _, p := range list {
+			titles = append(titles, p.Title)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, []string{"post 1", "post 2", "post 3", "post 4", "post 5"}, titles)
+}
+
+func TestFetchNothing(t *testing.T) {
+	a := postRepo.NewMemoryPostRepository()
+	require.NoError(t, a.Store(context.TODO(), &domain.Post{Title: "Title"}))
+
+	for _, num := range []int64{0, -1} {
+		list, next, err := a.Fetch(context.TODO(), "", num)
+
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		assert.Empty(t, next)
+	}
+}
+
+func TestConcurrentStore(t *testing.T) {
+	a := postRepo.NewMemoryPostRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, a.Store(context.TODO(), &domain.Post{Title: fmt.Sprintf("post %d", i)}))
+			_, _, err := a.Fetch(context.TODO(), "", 10)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	list, _, err := a.Fetch(context.TODO(), "", 100)
+	require.NoError(t, err)
+	assert.Len(t, list, 50)
+}
@@ -0,0 +1,239 @@
+package sqlrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type sqlPostRepo struct {
+	Router  *repository.Router
+	Dialect repository.Dialect
+	Logger  logrus.FieldLogger
+	Stmts   *repository.StmtCache
+}
+
+// NewSQLPostRepository will create an implementation of post repository for the sql engine spoken by dialect.
+// Reads go to the database router picks for them. The repository prepares its statements once,
+// it implements io.Closer to release them.
+func NewSQLPostRepository(router *repository.Router, dialect repository.Dialect, logger logrus.FieldLogger) domain.PostRepository {
+	return &sqlPostRepo{
+		Router:  router,
+		Dialect: dialect,
+		Logger:  logger,
+		Stmts:   repository.NewStmtCache(router),
+	}
+}
+
+// Close closes the prepared statements
+func (p *sqlPostRepo) Close() error {
+	return p.Stmts.Close()
+}
+
+// table is the qualified name of the post table
+func (p *sqlPostRepo) table() string {
+	return p.Dialect.Table("post")
+}
+
+func (p *sqlPostRepo) Store(ctx context.Context, entry *domain.Post) (err error) {
+	query := `INSERT INTO ` + p.table() + ` (title, content, author_id, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?)`
+
+	// the timestamps left zero are set on insert, imported posts keep theirs. Both are stored at the
+	// precision of the columns so that they read back unchanged.
+	now := time.Now()
+	createdAt := stamp(entry.CreatedAt, now).Truncate(p.Dialect.Precision())
+	updatedAt := stamp(entry.UpdatedAt, now).Truncate(p.Dialect.Precision())
+
+	id, err := repository.Insert(ctx, p.Stmts, p.Dialect, query,
+		entry.Title, entry.Content, entry.Author.ID, p.Dialect.Time(createdAt), p.Dialect.Time(updatedAt))
+	if err != nil {
+		return
+	}
+
+	entry.ID = id
+	entry.CreatedAt = createdAt
+	entry.UpdatedAt = updatedAt
+	return
+}
+
+// stamp returns t in UTC, or now when t is zero
+func stamp(t, now time.Time) time.Time {
+	if t.IsZero() {
+		t = now
+	}
+
+	return t.UTC()
+}
+
+func (p *sqlPostRepo) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Post, err error) {
+	statement, err := p.Stmts.PrepareRead(ctx, repository.Rebind(p.Dialect, query))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := statement.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logging.FromContext(ctx, p.Logger).WithError(errRow).Error("closing rows")
+		}
+	}()
+
+	result = make([]domain.Post, 0)
+	for rows.Next() {
+		t := domain.Post{}
+		authorID := int64(0)
+
+		err = rows.Scan(
+			&t.ID,
+			&t.Title,
+			&t.Content,
+			&authorID,
+			&t.UpdatedAt,
+			&t.CreatedAt,
+		)
+
+		if err != nil {
+			logging.FromContext(ctx, p.Logger).WithError(err).Error("scanning row")
+			return nil, err
+		}
+
+		t.Author = domain.Author{
+			ID: authorID,
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+func (p *sqlPostRepo) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	query := `SELECT id, title, content, author_id, updated_at, created_at
+				FROM ` + p.table() + `
+				WHERE created_at > ? OR (created_at = ? AND id > ?)
+				ORDER BY created_at, id
+				LIMIT ?`
+
+	// the id breaks the ties of the creation times, which are only as precise as their columns
+	createdAt, id, err := repository.DecodeCursor(cursor)
+	if err != nil && cursor != "" {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	after := p.Dialect.Time(createdAt.UTC())
+	res, err = p.fetch(ctx, query, after, after, id, num)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(res) == int(num) {
+		last := res[len(res)-1]
+		nextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return
+}
+
+func (p *sqlPostRepo) GetByID(ctx context.Context, id int64) (res domain.Post, err error) {
+	query := `SELECT id, title, content, author_id, updated_at, created_at
+				FROM ` + p.table() + `
+				WHERE id = ?`
+
+	list, err := p.fetch(ctx, query, id)
+	if err != nil {
+		return
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *sqlPostRepo) GetByTitle(ctx context.Context, title string) (res domain.Post, err error) {
+	query := `SELECT id, title, content, author_id, updated_at, created_at
+				FROM ` + p.table() + `
+				WHERE title = ?`
+
+	list, err := p.fetch(ctx, query, title)
+	if err != nil {
+		return
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *sqlPostRepo) Update(ctx context.Context, entry *domain.Post) (err error) {
+	query := `UPDATE ` + p.table() + ` set title=?, content=?, author_id=?, updated_at=? WHERE id = ?`
+
+	statement, err := p.Stmts.Prepare(ctx, repository.Rebind(p.Dialect, query))
+	if err != nil {
+		return
+	}
+
+	res, err := statement.ExecContext(ctx, entry.Title, entry.Content, entry.Author.ID, p.Dialect.Time(entry.UpdatedAt), entry.ID)
+	if err != nil {
+		return p.Dialect.TranslateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affect == 0 {
+		return domain.ErrNotFound
+	}
+	if affect != 1 {
+		err = fmt.Errorf("Weird  Behavior. Total Affected: %d", affect)
+		return
+	}
+
+	return
+}
+
+func (p *sqlPostRepo) Delete(ctx context.Context, id int64) (err error) {
+	query := `DELETE FROM ` + p.table() + ` WHERE id = ?`
+
+	statement, err := p.Stmts.Prepare(ctx, repository.Rebind(p.Dialect, query))
+	if err != nil {
+		return
+	}
+
+	res, err := statement.ExecContext(ctx, id)
+	if err != nil {
+		return
+	}
+
+	rowAffected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rowAffected == 0 {
+		return domain.ErrNotFound
+	}
+	if rowAffected != 1 {
+		err = fmt.Errorf("Weird behavior. Total Affected %d", rowAffected)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,319 @@
+package sqlrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository/repositorytest"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
+	postRepo "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/sqlrepo"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+// the statements each dialect is expected to run, with the duplicate key error of its driver
+var dialects = []struct {
+	kind      string
+	selectAll string
+	insert    string
+	update    string
+	delete    string
+	duplicate error
+}{
+	{
+		kind:      "postgres",
+		selectAll: `SELECT id, title, content, author_id, updated_at, created_at FROM public."post"`,
+		insert:    `INSERT INTO public."post" (title, content, author_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		update:    `UPDATE public."post" set title=$1, content=$2, author_id=$3, updated_at=$4 WHERE id = $5`,
+		delete:    `DELETE FROM public."post" WHERE id = $1`,
+		duplicate: &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint \"post_title_key\""},
+	},
+	{
+		kind:      "mysql",
+		selectAll: "SELECT id, title, content, author_id, updated_at, created_at FROM `post`",
+		insert:    "INSERT INTO `post` (title, content, author_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		update:    "UPDATE `post` set title=?, content=?, author_id=?, updated_at=? WHERE id = ?",
+		delete:    "DELETE FROM `post` WHERE id = ?",
+		duplicate: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Judul' for key 'post_title_unique'"},
+	},
+}
+
+func newDialect(t *testing.T, kind string) repository.Dialect {
+	d, err := repository.DialectFor(kind)
+	require.NoError(t, err)
+	return d
+}
+
+func TestFetch(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			now := time.Now()
+			rows := sqlmock.NewRows([]string{"id", "title", "content", "author_id", "updated_at", "created_at"}).
+				AddRow(1, "title 1", "content 1", 1, now, now).
+				AddRow(2, "title 2", "content 2", 1, now, now)
+
+			query := dc.selectAll + " WHERE created_at > " + d.Placeholder(1) + " OR (created_at = " + d.Placeholder(2) +
+				" AND id > " + d.Placeholder(3) + ") ORDER BY created_at, id LIMIT " + d.Placeholder(4)
+			mock.ExpectPrepare(regexp.QuoteMeta(query)).ExpectQuery().
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7), int64(2)).WillReturnRows(rows)
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			list, nextCursor, err := entry.Fetch(context.TODO(), repository.EncodeCursor(now, 7), 2)
+
+			assert.NoError(t, err)
+			assert.Len(t, list, 2)
+			assert.Equal(t, repository.EncodeCursor(now, 2), nextCursor)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetByIDAndTitle(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			rows := sqlmock.NewRows([]string{"id", "title", "content", "author_id", "updated_at", "created_at"}).
+				AddRow(1, "title 1", "Content 1", 1, time.Now(), time.Now())
+			mock.ExpectPrepare(regexp.QuoteMeta(dc.selectAll + " WHERE id = " + d.Placeholder(1))).ExpectQuery().WithArgs(1).WillReturnRows(rows)
+			mock.ExpectPrepare(regexp.QuoteMeta(dc.selectAll + " WHERE title = " + d.Placeholder(1))).ExpectQuery().WithArgs("unknown").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author_id", "updated_at", "created_at"}))
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+
+			anPost, err := entry.GetByID(context.TODO(), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "title 1", anPost.Title)
+			assert.Equal(t, int64(1), anPost.Author.ID)
+
+			_, err = entry.GetByTitle(context.TODO(), "unknown")
+			assert.Equal(t, domain.ErrNotFound, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			post := &domain.Post{Title: "Judul", Content: "Content", Author: domain.Author{ID: 1, Name: "Dummy User"}}
+
+			prep := mock.ExpectPrepare(regexp.QuoteMeta(dc.insert))
+			args := []driver.Value{post.Title, post.Content, post.Author.ID, sqlmock.AnyArg(), sqlmock.AnyArg()}
+			if d.Returning() {
+				prep.ExpectQuery().WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+			} else {
+				prep.ExpectExec().WithArgs(args...).WillReturnResult(sqlmock.NewResult(12, 1))
+			}
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			err = entry.Store(context.TODO(), post)
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(12), post.ID)
+			assert.False(t, post.CreatedAt.IsZero())
+			assert.Equal(t, post.CreatedAt, post.UpdatedAt)
+			assert.Equal(t, post.CreatedAt, post.CreatedAt.Truncate(d.Precision()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStoreConflict(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			prep := mock.ExpectPrepare(regexp.QuoteMeta(dc.insert))
+			if d.Returning() {
+				prep.ExpectQuery().WillReturnError(dc.duplicate)
+			} else {
+				prep.ExpectExec().WillReturnError(dc.duplicate)
+			}
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			err = entry.Store(context.TODO(), &domain.Post{Title: "Judul", Content: "Content"})
+
+			assert.Equal(t, domain.ErrConflict, err)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			now := time.Now()
+			post := &domain.Post{ID: 12, Title: "Judul", Content: "Content", UpdatedAt: now, Author: domain.Author{ID: 1}}
+
+			prep := mock.ExpectPrepare(regexp.QuoteMeta(dc.update))
+			prep.ExpectExec().
+				WithArgs(post.Title, post.Content, post.Author.ID, now, post.ID).
+				WillReturnResult(sqlmock.NewResult(12, 1))
+			prep.ExpectExec().WillReturnError(dc.duplicate)
+			prep.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+
+			assert.NoError(t, entry.Update(context.TODO(), post))
+			assert.Equal(t, domain.ErrConflict, entry.Update(context.TODO(), post))
+			assert.Equal(t, domain.ErrNotFound, entry.Update(context.TODO(), post))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			prep := mock.ExpectPrepare(regexp.QuoteMeta(dc.delete))
+			prep.ExpectExec().WithArgs(12).WillReturnResult(sqlmock.NewResult(12, 1))
+			prep.ExpectExec().WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+
+			assert.NoError(t, entry.Delete(context.TODO(), 12))
+			assert.Equal(t, domain.ErrNotFound, entry.Delete(context.TODO(), 12))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostRepositorySuite(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		kind := kind
+		t.Run(kind, func(t *testing.T) {
+			repositorytest.RunPostRepositorySuite(t, func(t *testing.T) domain.PostRepository {
+				db, d := repositorytest.OpenDatabase(t, kind)
+				return postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			})
+		})
+	}
+}
+
+// TestStoreConcurrentTitle runs on a sqlite file, and against the real databases given by repositorytest.DSNVariables
+func TestStoreConcurrentTitle(t *testing.T) {
+	for _, kind := range []string{"sqlite", "postgres", "mysql"} {
+		kind := kind
+		t.Run(kind, func(t *testing.T) {
+			db, d := repositorytest.OpenDatabase(t, kind)
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+
+			title := fmt.Sprintf("concurrent %d", time.Now().UnixNano())
+
+			const attempts = 10
+			var wg sync.WaitGroup
+			errs := make(chan error, attempts)
+			for i := 0; i < attempts; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- entry.Store(context.TODO(), &domain.Post{Title: title, Content: "Content", Author: domain.Author{ID: 1}})
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			stored, conflicts := 0, 0
+			for err := range errs {
+				switch err {
+				case nil:
+					stored++
+				case domain.ErrConflict:
+					conflicts++
+				default:
+					t.Errorf("unexpected error: %s", err)
+				}
+			}
+
+			assert.Equal(t, 1, stored)
+			assert.Equal(t, attempts-1, conflicts)
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	for _, dc := range dialects {
+		t.Run(dc.kind, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			d := newDialect(t, dc.kind)
+
+			mock.ExpectPrepare(regexp.QuoteMeta(dc.delete)).WillBeClosed().
+				ExpectExec().WithArgs(12).WillReturnResult(sqlmock.NewResult(12, 1))
+
+			entry := postRepo.NewSQLPostRepository(repository.NewRouter(db, nil, 0, nil), d, logrus.New())
+			require.NoError(t, entry.Delete(context.TODO(), 12))
+
+			closer, ok := entry.(io.Closer)
+			require.True(t, ok)
+			assert.NoError(t, closer.Close())
+			assert.Equal(t, repository.ErrStmtCacheClosed, entry.Delete(context.TODO(), 12))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReadsGoToReplica(t *testing.T) {
+	dc := dialects[0]
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	d := newDialect(t, dc.kind)
+
+	columns := []string{"id", "title", "content", "author_id", "updated_at", "created_at"}
+	getByID := regexp.QuoteMeta(dc.selectAll + " WHERE id = " + d.Placeholder(1))
+	replicaMock.ExpectPrepare(getByID).ExpectQuery().WithArgs(12).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(12, "Judul", "Content", 1, time.Now(), time.Now()))
+	primaryMock.ExpectPrepare(regexp.QuoteMeta(dc.delete)).ExpectExec().WithArgs(12).WillReturnResult(sqlmock.NewResult(12, 1))
+	primaryMock.ExpectPrepare(getByID).ExpectQuery().WithArgs(12).WillReturnRows(sqlmock.NewRows(columns))
+
+	router := repository.NewRouter(primary, []*sql.DB{replica}, time.Minute, logrus.New())
+	router.CheckReplicas(context.TODO(), time.Second)
+	entry := postRepo.NewSQLPostRepository(router, d, logrus.New())
+	ctx := domain.NewContextWithRequestInfo(context.TODO(), domain.RequestInfo{Actor: "editor", IP: "10.0.0.1"})
+
+	_, err = entry.GetByID(ctx, 12)
+	assert.NoError(t, err)
+
+	// the client reads its own delete from the primary
+	assert.NoError(t, entry.Delete(ctx, 12))
+	_, err = entry.GetByID(ctx, 12)
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
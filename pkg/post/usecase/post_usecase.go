@@ -2,8 +2,12 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"sync"
 	"time"
 
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/deadline"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/logging"
 	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -12,15 +16,21 @@ import (
 type postUsecase struct {
 	postRepo       domain.PostRepository
 	authorRepo     domain.AuthorRepository
+	auditRepo      domain.AuditRepository
+	txManager      domain.TxManager
 	contextTimeout time.Duration
+	logger         logrus.FieldLogger
 }
 
 // NewPostUsecase will create new an postUsecase object representation of domain.PostUsecase interface
-func NewPostUsecase(pr domain.PostRepository, ar domain.AuthorRepository, timeout time.Duration) domain.PostUsecase {
+func NewPostUsecase(pr domain.PostRepository, ar domain.AuthorRepository, adr domain.AuditRepository, tm domain.TxManager, timeout time.Duration, logger logrus.FieldLogger) domain.PostUsecase {
 	return &postUsecase{
 		postRepo:       pr,
 		authorRepo:     ar,
+		auditRepo:      adr,
+		txManager:      tm,
 		contextTimeout: timeout,
+		logger:         logger,
 	}
 }
 
@@ -28,15 +38,14 @@ func (p *postUsecase) fillAuthorDetails(c context.Context, data []domain.Post) (
 	g, ctx := errgroup.WithContext(c)
 
 	// Get author's id
-	mapAuthors := map[int64]domain.Author{}
-
+	authorIDs := map[int64]struct{}{}
 	for _, post := range data {
-		mapAuthors[post.Author.ID] = domain.Author{}
+		authorIDs[post.Author.ID] = struct{}{}
 	}
 
-	// channel author
-	chanAuthor := make(chan domain.Author)
-	for authorID := range mapAuthors {
+	var mu sync.Mutex
+	mapAuthors := make(map[int64]domain.Author, len(authorIDs))
+	for authorID := range authorIDs {
 		authorID := authorID
 		g.Go(func() error {
 			res, err := p.authorRepo.GetByID(ctx, authorID)
@@ -44,33 +53,21 @@ func (p *postUsecase) fillAuthorDetails(c context.Context, data []domain.Post) (
 				return err
 			}
 
-			chanAuthor <- res
+			mu.Lock()
+			mapAuthors[authorID] = res
+			mu.Unlock()
 			return nil
 		})
 	}
 
-	go func() {
-		err := g.Wait()
-		if err != nil {
-			logrus.Error(err)
-			return
-		}
-		close(chanAuthor)
-	}()
-
-	for author := range chanAuthor {
-		if author != (domain.Author{}) {
-			mapAuthors[author.ID] = author
-		}
-	}
-
 	if err := g.Wait(); err != nil {
+		logging.FromContext(c, p.logger).WithError(err).Error("fetching the authors")
 		return nil, err
 	}
 
 	// merge the author's data to post's data
 	for i, item := range data {
-		if a, ok := mapAuthors[item.Author.ID]; ok {
+		if a, ok := mapAuthors[item.Author.ID]; ok && a != (domain.Author{}) {
 			data[i].Author = a
 		}
 	}
@@ -79,25 +76,29 @@ func (p *postUsecase) fillAuthorDetails(c context.Context, data []domain.Post) (
 }
 
 func (p *postUsecase) Store(c context.Context, e *domain.Post) error {
-	ctx, cancel := context.WithTimeout(c, p.contextTimeout)
+	ctx, cancel := deadline.WithDefault(c, p.contextTimeout)
 	defer cancel()
 
-	// Check if post already posted
-	existedPost, _ := p.GetByTitle(ctx, e.Title)
-	if existedPost != (domain.Post{}) {
-		return domain.ErrConflict
-	}
+	return p.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// the unique index on title rejects an already posted post with domain.ErrConflict
+		err := p.postRepo.Store(ctx, e)
+		if err != nil {
+			return err
+		}
 
-	err := p.postRepo.Store(ctx, e)
-	return err
+		return p.recordAudit(ctx, domain.AuditActionCreate, e.ID, nil, e)
+	})
 }
 
 func (p *postUsecase) Fetch(c context.Context, cursor string, num int64) (res []domain.Post, nextCursor string, err error) {
+	if num < 0 {
+		return nil, "", domain.ErrBadParamInput
+	}
 	if num == 0 {
 		num = 10
 	}
 
-	ctx, cancel := context.WithTimeout(c, p.contextTimeout)
+	ctx, cancel := deadline.WithDefault(c, p.contextTimeout)
 	defer cancel()
 
 	res, nextCursor, err = p.postRepo.Fetch(ctx, cursor, num)
@@ -114,7 +115,7 @@ func (p *postUsecase) Fetch(c context.Context, cursor string, num int64) (res []
 }
 
 func (p *postUsecase) GetByID(c context.Context, id int64) (res domain.Post, err error) {
-	ctx, cancel := context.WithTimeout(c, p.contextTimeout)
+	ctx, cancel := deadline.WithDefault(c, p.contextTimeout)
 	defer cancel()
 
 	res, err = p.postRepo.GetByID(ctx, id)
@@ -132,7 +133,7 @@ func (p *postUsecase) GetByID(c context.Context, id int64) (res domain.Post, err
 }
 
 func (p *postUsecase) GetByTitle(c context.Context, title string) (res domain.Post, err error) {
-	ctx, cancel := context.WithTimeout(c, p.contextTimeout)
+	ctx, cancel := deadline.WithDefault(c, p.contextTimeout)
 	defer cancel()
 
 	res, err = p.postRepo.GetByTitle(ctx, title)
@@ -150,26 +151,75 @@ func (p *postUsecase) GetByTitle(c context.Context, title string) (res domain.Po
 }
 
 func (p *postUsecase) Update(c context.Context, e *domain.Post) (err error) {
-	ctx, cancel := context.WithTimeout(c, p.contextTimeout)
+	ctx, cancel := deadline.WithDefault(c, p.contextTimeout)
 	defer cancel()
 
-	e.UpdatedAt = time.Now()
-	return p.postRepo.Update(ctx, e)
+	return p.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		existedPost, err := p.postRepo.GetByID(ctx, e.ID)
+		if err != nil {
+			return err
+		}
+
+		e.UpdatedAt = time.Now()
+		err = p.postRepo.Update(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return p.recordAudit(ctx, domain.AuditActionUpdate, e.ID, &existedPost, e)
+	})
 }
 
 func (p *postUsecase) Delete(c context.Context, id int64) (err error) {
-	ctx, cancel := context.WithTimeout(c, p.contextTimeout)
+	ctx, cancel := deadline.WithDefault(c, p.contextTimeout)
 	defer cancel()
 
-	// check existedpost
-	existedPost, err := p.postRepo.GetByID(ctx, id)
-	if err != nil {
-		return
+	return p.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// check existedpost
+		existedPost, err := p.postRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if existedPost == (domain.Post{}) {
+			return domain.ErrNotFound
+		}
+
+		err = p.postRepo.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return p.recordAudit(ctx, domain.AuditActionDelete, id, &existedPost, nil)
+	})
+}
+
+// recordAudit appends an audit event for a post mutation, it must run in the mutation's transaction
+func (p *postUsecase) recordAudit(ctx context.Context, action string, id int64, before, after *domain.Post) (err error) {
+	info := domain.RequestInfoFromContext(ctx)
+	event := domain.AuditEvent{
+		Actor:     info.Actor,
+		Action:    action,
+		Entity:    domain.AuditEntityPost,
+		EntityID:  id,
+		RequestID: info.RequestID,
+		IP:        info.IP,
+		CreatedAt: time.Now(),
+	}
+
+	if before != nil {
+		event.Before, err = json.Marshal(before)
+		if err != nil {
+			return
+		}
 	}
 
-	if existedPost == (domain.Post{}) {
-		return domain.ErrNotFound
+	if after != nil {
+		event.After, err = json.Marshal(after)
+		if err != nil {
+			return
+		}
 	}
 
-	return p.postRepo.Delete(ctx, id)
+	return p.auditRepo.Store(ctx, &event)
 }
@@ -6,13 +6,29 @@ import (
 	"testing"
 	"time"
 
+	_auditRepoMemory "github.com/ilmimris/poc-gofiber-clean-arch/pkg/audit/repository/memory"
+	_authorRepoMemory "github.com/ilmimris/poc-gofiber-clean-arch/pkg/author/repository/memory"
+	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/common/repository"
 	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain"
 	"github.com/ilmimris/poc-gofiber-clean-arch/pkg/domain/mocks"
+	_postRepoMemory "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/repository/memory"
 	ucase "github.com/ilmimris/poc-gofiber-clean-arch/pkg/post/usecase"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+// newMockTxManager returns a TxManager mock that runs the given function as if the transaction commits
+func newMockTxManager() *mocks.TxManager {
+	mockTx := new(mocks.TxManager)
+	mockTx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	})
+
+	return mockTx
+}
+
 func TestFetch(t *testing.T) {
 	mockPostRepo := new(mocks.PostRepository)
 	mockPost := domain.Post{
@@ -32,7 +48,7 @@ func TestFetch(t *testing.T) {
 		}
 		mockAuthorrepo := new(mocks.AuthorRepository)
 		mockAuthorrepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(mockAuthor, nil)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
 		num := int64(1)
 		cursor := "12"
 		list, nextCursor, err := u.Fetch(context.TODO(), cursor, num)
@@ -51,7 +67,7 @@ func TestFetch(t *testing.T) {
 			mock.AnythingOfType("int64")).Return(nil, "", errors.New("Unexpexted Error")).Once()
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
 		num := int64(1)
 		cursor := "12"
 		list, nextCursor, err := u.Fetch(context.TODO(), cursor, num)
@@ -63,6 +79,54 @@ func TestFetch(t *testing.T) {
 		mockAuthorrepo.AssertExpectations(t)
 	})
 
+	t.Run("negative-num", func(t *testing.T) {
+		u := ucase.NewPostUsecase(mockPostRepo, new(mocks.AuthorRepository), new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
+
+		list, nextCursor, err := u.Fetch(context.TODO(), "", -1)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+		assert.Empty(t, nextCursor)
+		assert.Len(t, list, 0)
+		mockPostRepo.AssertExpectations(t)
+	})
+
+	t.Run("error-author", func(t *testing.T) {
+		mockPostRepo.On("Fetch", mock.Anything, mock.AnythingOfType("string"),
+			mock.AnythingOfType("int64")).Return(mockListArtilce, "next-cursor", nil).Once()
+
+		mockAuthorrepo := new(mocks.AuthorRepository)
+		mockAuthorrepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(domain.Author{}, domain.ErrUnavailable)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
+
+		list, nextCursor, err := u.Fetch(context.TODO(), "12", 1)
+
+		assert.Equal(t, domain.ErrUnavailable, err)
+		assert.Empty(t, nextCursor)
+		assert.Len(t, list, 0)
+		mockPostRepo.AssertExpectations(t)
+		mockAuthorrepo.AssertExpectations(t)
+	})
+
+	t.Run("error-author-deadline", func(t *testing.T) {
+		mockPostRepo.On("Fetch", mock.Anything, mock.AnythingOfType("string"),
+			mock.AnythingOfType("int64")).Return(mockListArtilce, "next-cursor", nil).Once()
+
+		// a slow author lookup outlives the deadline of the request
+		mockAuthorrepo := new(mocks.AuthorRepository)
+		mockAuthorrepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).Return(domain.Author{}, context.DeadlineExceeded)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		defer cancel()
+		list, nextCursor, err := u.Fetch(ctx, "12", 1)
+
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Empty(t, nextCursor)
+		assert.Len(t, list, 0)
+		mockAuthorrepo.AssertExpectations(t)
+	})
 }
 
 func TestGetByID(t *testing.T) {
@@ -80,7 +144,7 @@ func TestGetByID(t *testing.T) {
 		mockPostRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(mockPost, nil).Once()
 		mockAuthorrepo := new(mocks.AuthorRepository)
 		mockAuthorrepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(mockAuthor, nil)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
 
 		a, err := u.GetByID(context.TODO(), mockPost.ID)
 
@@ -94,7 +158,7 @@ func TestGetByID(t *testing.T) {
 		mockPostRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(domain.Post{}, errors.New("Unexpected")).Once()
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), new(mocks.TxManager), time.Second*2, logrus.New())
 
 		a, err := u.GetByID(context.TODO(), mockPost.ID)
 
@@ -117,35 +181,33 @@ func TestStore(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		tempMockPost := mockPost
 		tempMockPost.ID = 0
-		mockPostRepo.On("GetByTitle", mock.Anything, mock.AnythingOfType("string")).Return(domain.Post{}, domain.ErrNotFound).Once()
 		mockPostRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(nil).Once()
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		mockAuditRepo := new(mocks.AuditRepository)
+		mockAuditRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.AuditEvent")).Return(nil).Once()
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, mockAuditRepo, newMockTxManager(), time.Second*2, logrus.New())
 
 		err := u.Store(context.TODO(), &tempMockPost)
 
 		assert.NoError(t, err)
 		assert.Equal(t, mockPost.Title, tempMockPost.Title)
 		mockPostRepo.AssertExpectations(t)
+		mockAuditRepo.AssertExpectations(t)
 	})
 	t.Run("existing-title", func(t *testing.T) {
 		existingPost := mockPost
-		mockPostRepo.On("GetByTitle", mock.Anything, mock.AnythingOfType("string")).Return(existingPost, nil).Once()
-		mockAuthor := domain.Author{
-			ID:   1,
-			Name: "Iman Tumorang",
-		}
-		mockAuthorrepo := new(mocks.AuthorRepository)
-		mockAuthorrepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(mockAuthor, nil)
+		mockPostRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(domain.ErrConflict).Once()
 
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		mockAuthorrepo := new(mocks.AuthorRepository)
+		mockAuditRepo := new(mocks.AuditRepository)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, mockAuditRepo, newMockTxManager(), time.Second*2, logrus.New())
 
-		err := u.Store(context.TODO(), &mockPost)
+		err := u.Store(context.TODO(), &existingPost)
 
-		assert.Error(t, err)
+		assert.Equal(t, domain.ErrConflict, err)
 		mockPostRepo.AssertExpectations(t)
-		mockAuthorrepo.AssertExpectations(t)
+		mockAuditRepo.AssertExpectations(t)
 	})
 
 }
@@ -163,7 +225,9 @@ func TestDelete(t *testing.T) {
 		mockPostRepo.On("Delete", mock.Anything, mock.AnythingOfType("int64")).Return(nil).Once()
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		mockAuditRepo := new(mocks.AuditRepository)
+		mockAuditRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.AuditEvent")).Return(nil).Once()
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, mockAuditRepo, newMockTxManager(), time.Second*2, logrus.New())
 
 		err := u.Delete(context.TODO(), mockPost.ID)
 
@@ -175,7 +239,7 @@ func TestDelete(t *testing.T) {
 		mockPostRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(domain.Post{}, nil).Once()
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), newMockTxManager(), time.Second*2, logrus.New())
 
 		err := u.Delete(context.TODO(), mockPost.ID)
 
@@ -187,7 +251,7 @@ func TestDelete(t *testing.T) {
 		mockPostRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(domain.Post{}, errors.New("Unexpected Error")).Once()
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, new(mocks.AuditRepository), newMockTxManager(), time.Second*2, logrus.New())
 
 		err := u.Delete(context.TODO(), mockPost.ID)
 
@@ -207,13 +271,91 @@ func TestUpdate(t *testing.T) {
 	}
 
 	t.Run("success", func(t *testing.T) {
+		mockPostRepo.On("GetByID", mock.Anything, mockPost.ID).Return(mockPost, nil).Once()
 		mockPostRepo.On("Update", mock.Anything, &mockPost).Once().Return(nil)
 
 		mockAuthorrepo := new(mocks.AuthorRepository)
-		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, time.Second*2)
+		mockAuditRepo := new(mocks.AuditRepository)
+		mockAuditRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.AuditEvent")).Return(nil).Once()
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, mockAuditRepo, newMockTxManager(), time.Second*2, logrus.New())
+
+		ctx := domain.NewContextWithRequestInfo(context.TODO(), domain.RequestInfo{
+			Actor:     "editor",
+			RequestID: "req-1",
+			IP:        "10.0.0.1",
+		})
+		err := u.Update(ctx, &mockPost)
+		assert.NoError(t, err)
+		mockPostRepo.AssertExpectations(t)
+		mockAuditRepo.AssertExpectations(t)
+
+		event := mockAuditRepo.Calls[0].Arguments.Get(1).(*domain.AuditEvent)
+		assert.Equal(t, "editor", event.Actor)
+		assert.Equal(t, domain.AuditActionUpdate, event.Action)
+		assert.Equal(t, domain.AuditEntityPost, event.Entity)
+		assert.Equal(t, mockPost.ID, event.EntityID)
+		assert.Equal(t, "req-1", event.RequestID)
+		assert.Equal(t, "10.0.0.1", event.IP)
+		assert.NotEmpty(t, event.Before)
+		assert.NotEmpty(t, event.After)
+	})
+	t.Run("audit-failed", func(t *testing.T) {
+		mockPostRepo.On("GetByID", mock.Anything, mockPost.ID).Return(mockPost, nil).Once()
+		mockPostRepo.On("Update", mock.Anything, &mockPost).Once().Return(nil)
+
+		mockAuthorrepo := new(mocks.AuthorRepository)
+		mockAuditRepo := new(mocks.AuditRepository)
+		mockAuditRepo.On("Store", mock.Anything, mock.AnythingOfType("*domain.AuditEvent")).Return(errors.New("Unexpected Error")).Once()
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, mockAuditRepo, newMockTxManager(), time.Second*2, logrus.New())
 
 		err := u.Update(context.TODO(), &mockPost)
-		assert.NoError(t, err)
+		assert.Error(t, err)
+		mockPostRepo.AssertExpectations(t)
+		mockAuditRepo.AssertExpectations(t)
+	})
+	t.Run("post-is-not-exist", func(t *testing.T) {
+		mockPostRepo.On("GetByID", mock.Anything, mockPost.ID).Return(domain.Post{}, domain.ErrNotFound).Once()
+
+		mockAuthorrepo := new(mocks.AuthorRepository)
+		mockAuditRepo := new(mocks.AuditRepository)
+		u := ucase.NewPostUsecase(mockPostRepo, mockAuthorrepo, mockAuditRepo, newMockTxManager(), time.Second*2, logrus.New())
+
+		err := u.Update(context.TODO(), &mockPost)
+		assert.Equal(t, domain.ErrNotFound, err)
 		mockPostRepo.AssertExpectations(t)
+		mockAuditRepo.AssertExpectations(t)
 	})
 }
+
+func TestWithMemoryRepositories(t *testing.T) {
+	postRepo := _postRepoMemory.NewMemoryPostRepository()
+	authorRepo := _authorRepoMemory.NewMemoryAuthorRepository()
+	auditRepo := _auditRepoMemory.NewMemoryAuditRepository()
+	u := ucase.NewPostUsecase(postRepo, authorRepo, auditRepo, repository.NewNoopTxManager(), time.Second*2, logrus.New())
+
+	author := domain.Author{Name: "Iman Tumorang"}
+	require.NoError(t, authorRepo.Store(context.TODO(), &author))
+
+	p := &domain.Post{Title: "Hello", Content: "Content", Author: author}
+	require.NoError(t, u.Store(context.TODO(), p))
+	assert.Equal(t, domain.ErrConflict, u.Store(context.TODO(), &domain.Post{Title: "Hello", Author: author}))
+
+	list, _, err := u.Fetch(context.TODO(), "", 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "Iman Tumorang", list[0].Author.Name)
+
+	p.Content = "Changed"
+	require.NoError(t, u.Update(context.TODO(), p))
+	require.NoError(t, u.Delete(context.TODO(), p.ID))
+
+	_, err = u.GetByID(context.TODO(), p.ID)
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	events, _, err := auditRepo.Fetch(context.TODO(), domain.AuditEntityPost, p.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, domain.AuditActionCreate, events[0].Action)
+	assert.Equal(t, domain.AuditActionUpdate, events[1].Action)
+	assert.Equal(t, domain.AuditActionDelete, events[2].Action)
+}
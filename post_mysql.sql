@@ -31,7 +31,8 @@ CREATE TABLE `post` (
   `author_id` int(11) DEFAULT '0',
   `updated_at` datetime DEFAULT NULL,
   `created_at` datetime DEFAULT NULL,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `post_title_unique` (`title`)
 ) ENGINE=InnoDB AUTO_INCREMENT=7 DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -97,6 +98,68 @@ INSERT INTO `author` VALUES (1,'Dummy User','2017-05-18 13:50:19','2017-05-18 13
 /*!40000 ALTER TABLE `author` ENABLE KEYS */;
 UNLOCK TABLES;
 
+--
+-- Table structure for table `audit_event`
+--
+
+DROP TABLE IF EXISTS `audit_event`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `audit_event` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `actor` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `action` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `entity` varchar(45) COLLATE utf8_unicode_ci NOT NULL,
+  `entity_id` bigint(20) NOT NULL,
+  `before_data` json DEFAULT NULL,
+  `after_data` json DEFAULT NULL,
+  `request_id` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `ip` varchar(45) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `created_at` datetime(6) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `audit_event_entity_idx` (`entity`,`entity_id`,`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+CREATE TRIGGER `audit_event_no_update` BEFORE UPDATE ON `audit_event` FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_event is append-only';
+CREATE TRIGGER `audit_event_no_delete` BEFORE DELETE ON `audit_event` FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_event is append-only';
+
+--
+-- Table structure for table `rate_limit_counter`
+--
+
+DROP TABLE IF EXISTS `rate_limit_counter`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `rate_limit_counter` (
+  `bucket` varchar(255) COLLATE utf8_unicode_ci NOT NULL,
+  `window_start` bigint(20) NOT NULL,
+  `hits` bigint(20) NOT NULL,
+  `expires_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`bucket`,`window_start`),
+  KEY `rate_limit_counter_expires_at_idx` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `idempotency_key`
+--
+
+DROP TABLE IF EXISTS `idempotency_key`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `idempotency_key` (
+  `idem_key` varchar(64) COLLATE utf8_unicode_ci NOT NULL,
+  `fingerprint` varchar(64) COLLATE utf8_unicode_ci NOT NULL,
+  `status` int(11) NOT NULL,
+  `content_type` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `body` longblob,
+  `expires_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`idem_key`),
+  KEY `idempotency_key_expires_at_idx` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `category`
 --
@@ -78,6 +78,71 @@ CREATE TABLE public.post_category (
 
 ALTER TABLE public.post_category OWNER TO "user";
 
+--
+-- Name: audit_event; Type: TABLE; Schema: public; Owner: user
+--
+
+CREATE TABLE public.audit_event (
+    id bigserial PRIMARY KEY,
+    actor character varying(200) NOT NULL,
+    action character varying(20) NOT NULL,
+    entity character varying(45) NOT NULL,
+    entity_id bigint NOT NULL,
+    before_data jsonb,
+    after_data jsonb,
+    request_id character varying(100),
+    ip character varying(45),
+    created_at timestamp(6) without time zone NOT NULL
+);
+
+
+ALTER TABLE public.audit_event OWNER TO "user";
+
+CREATE INDEX audit_event_entity_idx ON public.audit_event USING btree (entity, entity_id, id);
+
+--
+-- Name: audit_event; Type: RULE; Schema: public; Owner: user
+--
+
+CREATE RULE audit_event_no_update AS ON UPDATE TO public.audit_event DO INSTEAD NOTHING;
+
+CREATE RULE audit_event_no_delete AS ON DELETE TO public.audit_event DO INSTEAD NOTHING;
+
+--
+-- Name: rate_limit_counter; Type: TABLE; Schema: public; Owner: user
+--
+
+CREATE TABLE public.rate_limit_counter (
+    bucket character varying(255) NOT NULL,
+    window_start bigint NOT NULL,
+    hits bigint NOT NULL,
+    expires_at bigint NOT NULL,
+    PRIMARY KEY (bucket, window_start)
+);
+
+
+ALTER TABLE public.rate_limit_counter OWNER TO "user";
+
+CREATE INDEX rate_limit_counter_expires_at_idx ON public.rate_limit_counter USING btree (expires_at);
+
+--
+-- Name: idempotency_key; Type: TABLE; Schema: public; Owner: user
+--
+
+CREATE TABLE public.idempotency_key (
+    idem_key character varying(64) PRIMARY KEY,
+    fingerprint character varying(64) NOT NULL,
+    status integer NOT NULL,
+    content_type character varying(100) NOT NULL,
+    body bytea,
+    expires_at bigint NOT NULL
+);
+
+
+ALTER TABLE public.idempotency_key OWNER TO "user";
+
+CREATE INDEX idempotency_key_expires_at_idx ON public.idempotency_key USING btree (expires_at);
+
 --
 -- Data for Name: author; Type: TABLE DATA; Schema: public; Owner: user
 --
@@ -128,6 +193,14 @@ COPY public.post_category (id, post_id, category_id) FROM stdin;
 \.
 
 
+--
+-- Name: post post_title_key; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.post
+    ADD CONSTRAINT post_title_key UNIQUE (title);
+
+
 --
 -- PostgreSQL database dump complete
 --